@@ -3,65 +3,423 @@
 package analyzer
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/build"
+	"go/parser"
 	"go/token"
 	"go/types"
+	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"golang.org/x/mod/modfile"
 	"golang.org/x/tools/go/packages"
 
 	"github.com/don7panic/codewiki-go-analyzer/models"
 )
 
+// Analyzer analyzes a repository and reports what it found as an
+// AnalysisResult. GoAnalyzer is the implementation; callers that only run
+// an analysis and read its result can depend on this interface instead,
+// e.g. to substitute a fake in tests.
+type Analyzer interface {
+	Analyze() error
+	AnalyzeContext(ctx context.Context) error
+	Result() *models.AnalysisResult
+}
+
+var _ Analyzer = (*GoAnalyzer)(nil)
+
 type GoAnalyzer struct {
 	RepoPath         string
 	RepoAbs          string
 	FileSet          *token.FileSet
 	Nodes            []models.Node
 	Relationships    []models.CallRelationship
-	CollectedNodeIDs map[string]bool // Track collected node IDs for is_resolved
+	CollectedNodeIDs map[string]bool                       // Track collected node IDs for is_resolved
+	Partial          bool                                  // Set when analysis stopped before completing
+	Diagnostics      []string                              // Warnings about the analysis itself
+	Imports          map[string]map[string]string          // Relative path -> import name -> import path, when CollectImports is set
+	PackageDocs      map[string]string                     // Package path (or directory without a module) -> package doc, when CollectPackageDocs is set
+	Generate         map[string][]models.GenerateDirective // Relative path -> //go:generate directives, when CollectGenerate is set
+	Summary          models.Summary                        // Counts of the collected results, set by Analyze
+	GitCommit        string                                // Checked-out commit, when VCSInfo is set
+	ModuleVersion    string                                // Semver tag on GitCommit, when VCSInfo is set
+
+	// ProgressFunc, when set, is called as Analyze loads each module
+	// ("load") and visits each file in the node ("nodes") and relationship
+	// ("calls") passes, with 1-based current and total counts. Modules
+	// load concurrently, so "load" calls may come from different
+	// goroutines, though never at the same time.
+	ProgressFunc func(stage string, current, total int)
+
+	// Logger, when set, receives package load timings and a summary of
+	// the run at info level, and per-file node and relationship counts
+	// and skipped files at debug level. Nil disables logging.
+	Logger *slog.Logger
+
+	Options
+
+	nodeIndex     map[string]int               // Node ID -> index in Nodes
+	closureIDs    map[*ast.FuncLit]string      // Function literals extracted as closure nodes
+	initIDs       map[*ast.FuncDecl]string     // init functions -> numbered IDs
+	initCounts    map[string]int               // File path -> init functions seen
+	localTypeIDs  map[token.Pos]string         // Function-local type name positions -> synthetic IDs
+	importNames   map[string]map[string]string // File path -> import name -> package name, set by collectCalls
+	ignore        gitignore.Matcher            // Set by loadGitignore when UseGitignore is set
+	cache         *cacheRun                    // Set by AnalyzeContext when CacheDir is used
+	cacheHits     int                          // Modules served from CacheDir
+	localPackages []*types.Package             // Type-checked packages with files in the repo
+	localTypes    []*types.TypeName            // Lazily computed by repoTypeNames
+	funcAliases   map[*types.Var]*types.Func   // Package-level func vars -> the function they are initialized to
+	filePkgPaths  map[string]string            // Loaded file -> import path of its package
+	modulePaths   map[string]string            // Directory -> module path of its go.mod, "" without one
+	analyzed      bool                         // Set by AnalyzeContext, cleared by Reset
+}
+
+// AnalyzerOption configures a GoAnalyzer in NewGoAnalyzer.
+type AnalyzerOption func(*GoAnalyzer)
+
+// WithFileSet makes the analyzer record positions in fset instead of a
+// fresh FileSet, e.g. to share one across analyzers. Node positions and
+// offsets are only meaningful relative to the FileSet used.
+func WithFileSet(fset *token.FileSet) AnalyzerOption {
+	return func(a *GoAnalyzer) {
+		a.FileSet = fset
+	}
 }
 
-func NewGoAnalyzer(repoPath string) (*GoAnalyzer, error) {
+func NewGoAnalyzer(repoPath string, opts ...AnalyzerOption) (*GoAnalyzer, error) {
 	repoAbs, err := filepath.Abs(repoPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return &GoAnalyzer{
-		RepoPath:         repoPath,
-		RepoAbs:          repoAbs,
-		FileSet:          token.NewFileSet(),
-		Nodes:            []models.Node{},
-		Relationships:    []models.CallRelationship{},
-		CollectedNodeIDs: make(map[string]bool),
-	}, nil
+	a := &GoAnalyzer{
+		RepoPath: repoPath,
+		RepoAbs:  repoAbs,
+		Options:  DefaultOptions(),
+	}
+	a.Reset()
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a, nil
+}
+
+// Reset discards the results and state of previous runs, including the
+// FileSet (which grows with every file loaded), so the analyzer can run
+// again. Options, ProgressFunc and the repo path are kept; set FileSet
+// after Reset to keep using a specific one.
+func (a *GoAnalyzer) Reset() {
+	a.FileSet = token.NewFileSet()
+	a.Nodes = []models.Node{}
+	a.Relationships = []models.CallRelationship{}
+	a.CollectedNodeIDs = make(map[string]bool)
+	a.Partial = false
+	a.Diagnostics = nil
+	a.Imports = nil
+	a.PackageDocs = nil
+	a.Generate = nil
+	a.Summary = models.Summary{}
+	a.GitCommit = ""
+	a.ModuleVersion = ""
+	a.nodeIndex = make(map[string]int)
+	a.closureIDs = make(map[*ast.FuncLit]string)
+	a.initIDs = make(map[*ast.FuncDecl]string)
+	a.initCounts = make(map[string]int)
+	a.localTypeIDs = make(map[token.Pos]string)
+	a.importNames = make(map[string]map[string]string)
+	a.ignore = nil
+	a.cache = nil
+	a.cacheHits = 0
+	a.localPackages = nil
+	a.localTypes = nil
+	a.funcAliases = make(map[*types.Var]*types.Func)
+	a.filePkgPaths = make(map[string]string)
+	a.modulePaths = make(map[string]string)
+	a.analyzed = false
 }
 
+// Result returns the collected nodes and relationships with the analysis
+// metadata (Partial, Diagnostics, Imports, Summary, ...) as an
+// AnalysisResult. It shares the slices and maps of the analyzer, so it
+// reflects the last run until Reset.
+func (a *GoAnalyzer) Result() *models.AnalysisResult {
+	result := models.NewAnalysisResult(a.Nodes, a.Relationships)
+	result.Partial = a.Partial
+	result.Diagnostics = a.Diagnostics
+	result.Imports = a.Imports
+	result.PackageDocs = a.PackageDocs
+	result.Generate = a.Generate
+	summary := a.Summary
+	result.Summary = &summary
+	result.GitCommit = a.GitCommit
+	result.ModuleVersion = a.ModuleVersion
+	return &result
+}
+
+// Analyze loads the repository and collects nodes and relationships, which
+// Result then returns without any JSON encoding. It runs once per Reset: a
+// second call returns ErrAlreadyAnalyzed.
 func (a *GoAnalyzer) Analyze() error {
-	moduleRoots, err := a.findModuleRoots()
+	return a.AnalyzeContext(context.Background())
+}
+
+// AnalyzeContext is like Analyze but stops when ctx is done. Cancellation is
+// checked while loading packages and between files in the collection
+// passes; when it fires, the nodes and relationships collected so far are
+// kept, Partial is set and ctx.Err() is returned.
+func (a *GoAnalyzer) AnalyzeContext(ctx context.Context) error {
+	if a.analyzed {
+		return ErrAlreadyAnalyzed
+	}
+
+	switch a.IDStyle {
+	case "", IDStyleDottedPath, IDStyleImportPath, IDStyleSlashPath:
+	default:
+		return fmt.Errorf("unknown ID style %q", a.IDStyle)
+	}
+	if len(a.Files) > 0 && len(a.Patterns) > 0 {
+		return errors.New("cannot combine Files with Patterns")
+	}
+	for _, name := range a.StdlibInterfaces {
+		if _, _, ok := splitStdlibInterface(name); !ok {
+			return fmt.Errorf("invalid stdlib interface %q (want e.g. fmt.Stringer)", name)
+		}
+	}
+	for _, relType := range a.RelationshipTypes {
+		if !slices.Contains(relationshipTypes, relType) {
+			return fmt.Errorf("unknown relationship type %q (want one of %s)", relType, strings.Join(relationshipTypes, ", "))
+		}
+	}
+
+	// Invalid options leave the analyzer unused, so the caller can fix them
+	// and call Analyze again.
+	a.analyzed = true
+	start := time.Now()
+	if ctx.Err() != nil {
+		return a.stopEarly(ctx)
+	}
+
+	if a.UseGitignore && a.ignore == nil {
+		if err := a.loadGitignore(); err != nil {
+			return &ReadError{Path: a.RepoAbs, Err: err}
+		}
+	}
+
+	if a.VCSInfo {
+		if err := a.loadVCSInfo(); err != nil {
+			return &ReadError{Path: a.RepoAbs, Err: err}
+		}
+	}
+
+	// The cache covers whole-repo runs only; explicit patterns or files
+	// select a subset whose results would not match the per-module hashes.
+	if a.CacheDir != "" && len(a.Patterns) == 0 && len(a.Files) == 0 {
+		if err := a.openCache(ctx); err != nil {
+			a.Diagnostics = append(a.Diagnostics, fmt.Sprintf("not using the cache: %v", err))
+		} else if a.cacheHits > 0 {
+			a.log().Info("serving modules from cache", "modules", a.cacheHits, "of", len(a.cache.roots))
+		}
+	}
+
+	fileInfos, err := a.loadFiles(ctx)
 	if err != nil {
+		if ctx.Err() != nil {
+			return a.stopEarly(ctx)
+		}
 		return err
 	}
-	if len(moduleRoots) == 0 {
-		moduleRoots = []string{a.RepoAbs}
+	if len(fileInfos) == 0 && len(a.Patterns)+len(a.Files) > 0 {
+		return fmt.Errorf("%w: %s", ErrNoPackages, strings.Join(slices.Concat(a.Patterns, a.Files), " "))
+	}
+	filenames := a.analyzedFiles(fileInfos)
+	if err := a.collect(ctx, filenames, fileInfos); err != nil {
+		a.countFans()
+		a.summarize(filenames)
+		return err
 	}
 
+	if a.InternalAsPrivate {
+		a.markInternal()
+	}
+	stdlibStart := len(a.Diagnostics)
+	if len(a.StdlibInterfaces) > 0 && !a.restoreStdlibDiagnostics() {
+		a.markStdlibInterfaces()
+	}
+	var cacheData []byte
+	if a.cache != nil {
+		if cacheData, err = a.encodeCache(fileInfos, stdlibStart); err != nil {
+			return err
+		}
+	}
+	if a.ExportedOnly {
+		a.Nodes, a.Relationships = FilterExported(a.Nodes, a.Relationships)
+		a.CollectedNodeIDs = make(map[string]bool)
+		a.nodeIndex = make(map[string]int)
+		for i, node := range a.Nodes {
+			a.CollectedNodeIDs[node.ID] = true
+			a.nodeIndex[node.ID] = i
+		}
+	}
+
+	a.countFans()
+	a.summarize(filenames)
+
+	if cacheData != nil {
+		if err := a.saveCache(cacheData); err != nil {
+			a.Diagnostics = append(a.Diagnostics, fmt.Sprintf("could not write cache: %v", err))
+		}
+	}
+	a.log().Info("analysis finished", "files", len(filenames), "nodes", len(a.Nodes), "relationships", len(a.Relationships), "duration", time.Since(start))
+	return nil
+}
+
+// loadFiles loads every module in the repo and returns the repo files to
+// analyze, keyed by absolute path.
+func (a *GoAnalyzer) loadFiles(ctx context.Context) (map[string]*fileInfo, error) {
 	fileInfos := map[string]*fileInfo{}
+	diagnostics := len(a.Diagnostics)
+
+	// Explicit package patterns are handed to the go command as-is, like
+	// "go list <patterns>" run from the repo root.
+	moduleRoots := []string{a.RepoAbs}
+	patterns := a.Patterns
+	var rootPatterns map[string][]string
+	var selected map[string]bool
+	var loose []string
+	switch {
+	case len(a.Files) > 0:
+		var err error
+		moduleRoots, rootPatterns, selected, loose, err = a.filePatterns()
+		if err != nil {
+			return nil, err
+		}
+	case len(patterns) == 0:
+		var err error
+		moduleRoots, err = a.findModuleRoots()
+		if err != nil {
+			return nil, err
+		}
+		if len(moduleRoots) == 0 {
+			if cached := a.cachedModule(a.RepoAbs); cached != nil {
+				a.restoreModule(cached)
+				return fileInfos, nil
+			}
+			a.noModuleDiagnostic()
+		}
+		patterns = []string{"./..."}
+	}
 
-	for _, root := range moduleRoots {
-		pkgs, loadErr := a.loadPackages(root)
-		if loadErr != nil {
-			return loadErr
+	// Without any go.mod (or go.work) packages.Load has no module context,
+	// so fall back to plain parsing and syntactic call resolution.
+	if len(moduleRoots) == 0 {
+		if err := a.parseDirectory(fileInfos); err != nil {
+			return nil, err
+		}
+		a.recordLoad(a.RepoAbs, diagnostics)
+	}
+	// Named files outside every module, next to others inside one, are
+	// parsed on their own.
+	if len(moduleRoots) > 0 {
+		for _, file := range loose {
+			a.Diagnostics = append(a.Diagnostics, fmt.Sprintf("%s is not in a module: type resolution is disabled for it", a.logPath(file)))
+			if err := a.parseFile(file, fileInfos); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	loadPatterns := make([][]string, len(moduleRoots))
+	for i, root := range moduleRoots {
+		if rootPatterns != nil {
+			patterns = rootPatterns[root]
+		}
+		loadPatterns[i] = patterns
+		if a.FollowSymlinks && rootPatterns == nil && len(a.Patterns) == 0 {
+			links, err := a.symlinkPatterns(root)
+			if err != nil {
+				return nil, err
+			}
+			loadPatterns[i] = slices.Concat(patterns, links)
+		}
+		if a.IncludeVendor && rootPatterns == nil && len(a.Patterns) == 0 {
+			vendored, err := a.vendorPatterns(root)
+			if err != nil {
+				return nil, err
+			}
+			loadPatterns[i] = slices.Concat(loadPatterns[i], vendored)
+		}
+	}
+
+	// Module roots are loaded concurrently, up to Jobs at a time; their
+	// packages are then added in root order, so the results do not depend
+	// on which load finishes first. FileSet is safe for concurrent use.
+	// Modules served from the cache are not loaded.
+	loaded := make([][]*packages.Package, len(moduleRoots))
+	loadErrs := make([]error, len(moduleRoots))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, a.jobs())
+	done, total := 0, len(moduleRoots)-a.cacheHits
+	for i, root := range moduleRoots {
+		if a.cachedModule(root) != nil {
+			continue
+		}
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			start := time.Now()
+			loaded[i], loadErrs[i] = a.loadPackages(ctx, root, loadPatterns[i])
+			if loadErrs[i] == nil {
+				a.log().Info("loaded packages", "dir", a.logPath(root), "packages", len(loaded[i]), "duration", time.Since(start))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			a.progress("load", done, total)
+		})
+	}
+	wg.Wait()
+
+	for i, root := range moduleRoots {
+		if cached := a.cachedModule(root); cached != nil {
+			a.restoreModule(cached)
+			continue
+		}
+		if loadErrs[i] != nil {
+			return nil, &LoadError{Dir: root, Patterns: loadPatterns[i], Err: loadErrs[i]}
+		}
+		start := len(a.Diagnostics)
+		pkgs := loaded[i]
+		if a.IncludeTests {
+			pkgs = testVariants(pkgs)
 		}
 
 		for _, pkg := range pkgs {
+			inRepo := false
 			for _, file := range pkg.Syntax {
 				filename := a.FileSet.Position(file.Pos()).Filename
-				if filename == "" || isTestFile(filename) {
+				if filename == "" {
+					continue
+				}
+				if isTestFile(filename) && !a.IncludeTests {
+					a.logSkipped(filename, "test file")
 					continue
 				}
 				absPath, absErr := filepath.Abs(filename)
@@ -71,44 +429,307 @@ func (a *GoAnalyzer) Analyze() error {
 				if !isPathInRepo(a.RepoAbs, filename) {
 					continue
 				}
+				if a.isIgnored(filename, false) {
+					a.logSkipped(filename, "gitignored")
+					continue
+				}
+				inRepo = true
+				if a.ExcludeGenerated && ast.IsGenerated(file) {
+					a.logSkipped(filename, "generated")
+					continue
+				}
 				if _, exists := fileInfos[filename]; exists {
 					continue
 				}
-				content, readErr := os.ReadFile(filename)
-				if readErr != nil {
-					return readErr
+
+				source, err := a.newFileSource(filename, nil)
+				if err != nil {
+					return nil, err
 				}
+				a.filePkgPaths[filename] = pkg.PkgPath
 				fileInfos[filename] = &fileInfo{
 					file:    file,
 					info:    pkg.TypesInfo,
 					pkg:     pkg.Types,
-					content: content,
+					pkgName: pkg.Name,
+					pkgPath: pkg.PkgPath,
+					root:    root,
+					source:  source,
+				}
+			}
+			if inRepo && pkg.Types != nil {
+				a.localPackages = append(a.localPackages, pkg.Types)
+			}
+			if inRepo {
+				for _, pkgErr := range pkg.Errors {
+					if pkgErr.Kind == packages.ParseError {
+						a.parseErrorDiagnostic(pkgErr)
+					}
 				}
 			}
 		}
+		a.recordLoad(root, start)
+	}
+
+	// Files restricts emission to the named files; the rest of their
+	// packages was only loaded for type information.
+	if selected != nil {
+		for filename := range fileInfos {
+			if !selected[filename] {
+				delete(fileInfos, filename)
+			}
+		}
+	}
+
+	return fileInfos, nil
+}
+
+// filePatterns maps each of Files to the innermost module containing it and
+// returns the roots to load from, "file=" queries per root, the set of
+// selected absolute paths, and the files in no module. The go command loads
+// each package once however many of its files are named.
+func (a *GoAnalyzer) filePatterns() ([]string, map[string][]string, map[string]bool, []string, error) {
+	roots, err := a.findModuleRoots()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if len(roots) == 0 {
+		a.noModuleDiagnostic()
+	}
+
+	var usedRoots, loose []string
+	rootPatterns := map[string][]string{}
+	selected := map[string]bool{}
+	for _, file := range a.Files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(a.RepoAbs, file)
+		}
+		file = filepath.Clean(file)
+		if _, err := os.Stat(file); err != nil {
+			return nil, nil, nil, nil, &ReadError{Path: file, Err: err}
+		}
+		selected[file] = true
+
+		root := ""
+		for _, r := range roots {
+			if isPathInRepo(r, file) && len(r) > len(root) {
+				root = r
+			}
+		}
+		if root == "" {
+			loose = append(loose, file)
+			continue
+		}
+		if _, ok := rootPatterns[root]; !ok {
+			usedRoots = append(usedRoots, root)
+		}
+		rootPatterns[root] = append(rootPatterns[root], "file="+file)
+	}
+	return usedRoots, rootPatterns, selected, loose, nil
+}
+
+// analyzedFiles returns the sorted paths of the loaded files and of those
+// served from the cache.
+func (a *GoAnalyzer) analyzedFiles(fileInfos map[string]*fileInfo) []string {
+	filenames := make([]string, 0, len(fileInfos))
+	for filename := range fileInfos {
+		filenames = append(filenames, filename)
+	}
+	if a.cache != nil {
+		for filename := range a.cache.files {
+			filenames = append(filenames, filename)
+		}
+	}
+	sort.Strings(filenames)
+	return filenames
+}
+
+// collect runs the node and relationship passes over filenames, as sorted
+// by analyzedFiles. Files served from the cache get their cached results
+// in place, so the order is the same as when all are loaded.
+func (a *GoAnalyzer) collect(ctx context.Context, filenames []string, fileInfos map[string]*fileInfo) error {
+	var loaded []string
+	for _, filename := range filenames {
+		if fileInfos[filename] != nil {
+			loaded = append(loaded, filename)
+		}
 	}
 
 	// First pass: Collect nodes (Structs, Interfaces, Functions, Methods)
-	for filename, info := range fileInfos {
-		a.collectNodes(filename, info)
+	failed := map[string]bool{}
+	for i, filename := range filenames {
+		if ctx.Err() != nil {
+			return a.stopEarly(ctx)
+		}
+		if cached := a.cachedFile(filename); cached != nil {
+			a.restoreFileNodes(filename, cached)
+			a.progress("nodes", i+1, len(filenames))
+			continue
+		}
+		before := [3]int{len(a.Nodes), len(a.Relationships), len(a.Diagnostics)}
+		if !a.collectFile(filename, func() { a.collectNodes(filename, fileInfos[filename]) }) {
+			failed[filename] = true
+		}
+		if a.cache != nil {
+			a.recordPass(a.cache.nodeSpans, filename, before)
+		}
+		a.log().Debug("collected nodes", "file", a.logPath(filename), "nodes", len(a.Nodes)-before[0])
+		a.progress("nodes", i+1, len(filenames))
 	}
+	a.linkOwners()
+	overrides := len(a.Relationships)
+	a.linkOverrides()
+	a.restoreOverrides(overrides)
+	if a.PackageGraph {
+		a.collectPackageGraph(loaded, fileInfos)
+	}
+	a.collectFuncAliases(loaded, fileInfos)
 
 	// Second pass: Collect relationships (Calls)
-	for filename, info := range fileInfos {
-		a.collectCalls(filename, info)
+	for i, filename := range filenames {
+		if ctx.Err() != nil {
+			return a.stopEarly(ctx)
+		}
+		if cached := a.cachedFile(filename); cached != nil {
+			a.restoreFileCalls(cached)
+		} else if !failed[filename] {
+			before := [3]int{len(a.Nodes), len(a.Relationships), len(a.Diagnostics)}
+			a.collectFile(filename, func() { a.collectCalls(filename, fileInfos[filename]) })
+			if a.cache != nil {
+				a.recordPass(a.cache.callSpans, filename, before)
+			}
+			a.log().Debug("collected relationships", "file", a.logPath(filename), "relationships", len(a.Relationships)-before[1])
+		}
+		a.progress("calls", i+1, len(filenames))
 	}
 
 	return nil
 }
 
-func (a *GoAnalyzer) loadPackages(root string) ([]*packages.Package, error) {
+// collectFile runs one pass over filename and reports whether it completed.
+// A panic, e.g. on an AST left incomplete by a syntax error, is recorded as
+// a diagnostic instead, so one broken file does not abort the analysis.
+// Nodes and relationships collected before the panic are kept.
+func (a *GoAnalyzer) collectFile(filename string, pass func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.Diagnostics = append(a.Diagnostics, fmt.Sprintf("skipped the rest of %s after an internal error: %v", a.logPath(filename), r))
+			a.log().Warn("skipped the rest of a file after an internal error", "file", a.logPath(filename), "error", r)
+			ok = false
+		}
+	}()
+	pass()
+	return true
+}
+
+// parseErrorDiagnostic records a syntax error in a repo file. The file is
+// still analyzed, but declarations the parser could not make sense of are
+// missing from the results.
+func (a *GoAnalyzer) parseErrorDiagnostic(err error) {
+	a.Diagnostics = append(a.Diagnostics, fmt.Sprintf("%v; declarations around it may be missing", err))
+}
+
+func (a *GoAnalyzer) progress(stage string, current, total int) {
+	if a.ProgressFunc != nil {
+		a.ProgressFunc(stage, current, total)
+	}
+}
+
+// jobs returns how many module roots may be loaded at once.
+func (a *GoAnalyzer) jobs() int {
+	if a.Jobs > 0 {
+		return a.Jobs
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// noModuleDiagnostic explains why a repo without go.mod or go.work gets
+// syntactic-only results.
+func (a *GoAnalyzer) noModuleDiagnostic() {
+	a.Diagnostics = append(a.Diagnostics, fmt.Sprintf("no go.mod or go.work found under %s: type resolution is disabled and results are syntactic-only", a.RepoAbs))
+}
+
+// stopEarly marks the results as partial after ctx is done.
+func (a *GoAnalyzer) stopEarly(ctx context.Context) error {
+	a.Partial = true
+	a.Diagnostics = append(a.Diagnostics, fmt.Sprintf("analysis stopped early (%v); results are partial", ctx.Err()))
+	return ctx.Err()
+}
+
+func (a *GoAnalyzer) loadPackages(ctx context.Context, root string, patterns []string) ([]*packages.Package, error) {
 	cfg := &packages.Config{
-		Mode:  packages.NeedName | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedFiles,
-		Dir:   root,
-		Fset:  a.FileSet,
-		Tests: false,
+		Context: ctx,
+		Mode:    packages.NeedName | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedFiles,
+		Dir:     root,
+		Fset:    a.FileSet,
+		Tests:   a.IncludeTests,
 	}
-	return packages.Load(cfg, "./...")
+	if a.IncludeVendor && hasVendor(root) {
+		// Load vendored packages from vendor/ even when GOFLAGS says
+		// otherwise.
+		cfg.BuildFlags = []string{"-mod=vendor"}
+	}
+	return packages.Load(cfg, patterns...)
+}
+
+// parseDirectory parses every non-test .go file (all with IncludeTests)
+// under the repo root without type information. It is used when the repo
+// has no module, e.g. snippets or GOPATH-style trees.
+func (a *GoAnalyzer) parseDirectory(fileInfos map[string]*fileInfo) error {
+	return a.walkRepo(a.RepoAbs, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return &ReadError{Path: path, Err: err}
+		}
+		if d.IsDir() {
+			if path != a.RepoAbs && (a.skipsDir(d.Name()) || a.isIgnored(path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return a.parseFile(path, fileInfos)
+	})
+}
+
+// parseFile parses one .go file without type information into fileInfos,
+// skipping the same test, ignored and build-constrained files as
+// parseDirectory.
+func (a *GoAnalyzer) parseFile(path string, fileInfos map[string]*fileInfo) error {
+	if filepath.Ext(path) != ".go" || (isTestFile(path) && !a.IncludeTests) || a.isIgnored(path, false) {
+		return nil
+	}
+	if vendored, included := a.vendoredFile(path); vendored && !included {
+		return nil
+	}
+	if match, matchErr := build.Default.MatchFile(filepath.Dir(path), filepath.Base(path)); matchErr != nil || !match {
+		return nil
+	}
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return &ReadError{Path: path, Err: readErr}
+	}
+	file, parseErr := parser.ParseFile(a.FileSet, path, content, parser.ParseComments)
+	if file == nil {
+		return parseErr
+	}
+	if parseErr != nil {
+		a.parseErrorDiagnostic(parseErr)
+	}
+	if a.ExcludeGenerated && ast.IsGenerated(file) {
+		return nil
+	}
+	// The parsed file is kept, so unless read lazily, content is
+	// reused rather than read twice.
+	source, err := a.newFileSource(path, content)
+	if err != nil {
+		return err
+	}
+	fileInfos[path] = &fileInfo{
+		file:    file,
+		pkgName: file.Name.Name,
+		root:    a.RepoAbs,
+		source:  source,
+	}
+	return nil
 }
 
 func (a *GoAnalyzer) findModuleRoots() ([]string, error) {
@@ -117,13 +738,12 @@ func (a *GoAnalyzer) findModuleRoots() ([]string, error) {
 	}
 
 	roots := []string{}
-	err := filepath.WalkDir(a.RepoAbs, func(path string, d os.DirEntry, err error) error {
+	err := a.walkRepo(a.RepoAbs, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
-			return err
+			return &ReadError{Path: path, Err: err}
 		}
 		if d.IsDir() {
-			name := d.Name()
-			if name == ".git" || name == "vendor" || name == "node_modules" {
+			if isSkippedDir(d.Name()) || a.isIgnored(path, true) {
 				return filepath.SkipDir
 			}
 			return nil
@@ -136,6 +756,10 @@ func (a *GoAnalyzer) findModuleRoots() ([]string, error) {
 	return roots, err
 }
 
+func isSkippedDir(name string) bool {
+	return name == ".git" || name == "vendor" || name == "node_modules"
+}
+
 func isTestFile(path string) bool {
 	return strings.HasSuffix(path, "_test.go")
 }
@@ -144,33 +768,110 @@ type fileInfo struct {
 	file    *ast.File
 	info    *types.Info
 	pkg     *types.Package
-	content []byte
+	pkgName string // Name in the package clause
+	pkgPath string // Import path; empty without type information
+	root    string // Module root it was loaded from, or the repo root
+	source  *fileSource
 }
 
 func (a *GoAnalyzer) getComponentIDForFile(filePath string, name string, receiverType string) string {
 	// Mimic CodeWiki's ID generation: module_path.name
 	// models/Node.ID usually is fully qualified.
+	modulePath := a.idPrefix(filePath)
+	if modulePath == "" {
+		// Root package with IDStyleImportPath and no ModulePrefix.
+		return strings.TrimPrefix(fmt.Sprintf("%s.%s", receiverType, name), ".")
+	}
 
-	// We replace path.Dir separators to dots
+	if receiverType != "" {
+		return fmt.Sprintf("%s.%s.%s", modulePath, receiverType, name)
+	}
+	return fmt.Sprintf("%s.%s", modulePath, name)
+}
+
+// idPrefix returns the part of a component ID that identifies the file (or,
+// for IDStyleImportPath, the package) a declaration lives in.
+func (a *GoAnalyzer) idPrefix(filePath string) string {
 	relPath, _ := filepath.Rel(a.RepoAbs, filePath)
-	ext := filepath.Ext(relPath)
-	pathNoExt := relPath[:len(relPath)-len(ext)]
-	modulePath := ""
+	sep := "/"
+	switch a.IDStyle {
+	case IDStyleImportPath:
+		if importPath := a.importPath(filePath); importPath != "" {
+			return importPath
+		}
+		// Without a module there is no import path; use the directory.
+		relPath = filepath.Dir(relPath)
+		if relPath == "." {
+			relPath = ""
+		}
+	case IDStyleSlashPath:
+		relPath = relPath[:len(relPath)-len(filepath.Ext(relPath))]
+	default:
+		relPath = relPath[:len(relPath)-len(filepath.Ext(relPath))]
+		sep = "."
+	}
 
-	// Simple replace all separators with dots
-	// Note: This relies on standard forward slashes or OS separators
-	for _, c := range pathNoExt {
-		if os.IsPathSeparator(uint8(c)) {
-			modulePath += "."
-		} else {
-			modulePath += string(c)
+	// Normalize separators to slashes, then join with sep. Working on the
+	// string keeps multi-byte runes intact.
+	modulePath := strings.ReplaceAll(slashPath(relPath), "/", sep)
+
+	switch {
+	case a.ModulePrefix == "":
+		return modulePath
+	case modulePath == "":
+		return a.ModulePrefix
+	default:
+		return a.ModulePrefix + sep + modulePath
+	}
+}
+
+// importPath returns the import path of the package filePath belongs to:
+// the one it was loaded with or, for a file only known from a position
+// (e.g. a callee in another of the repo's modules), its directory below the
+// module path of the innermost go.mod. It is empty outside any module.
+func (a *GoAnalyzer) importPath(filePath string) string {
+	if importPath, ok := a.filePkgPaths[filePath]; ok {
+		return importPath
+	}
+	dir := filepath.Dir(filePath)
+	for root := dir; isPathInRepo(a.RepoAbs, root); root = filepath.Dir(root) {
+		modulePath := a.modulePath(root)
+		if modulePath == "" {
+			if root == filepath.Dir(root) {
+				break
+			}
+			continue
 		}
+		rel, _ := filepath.Rel(root, dir)
+		rel = slashPath(rel)
+		// Vendored packages are imported by their path below vendor/.
+		if vendored, ok := strings.CutPrefix(rel, "vendor/"); ok {
+			return vendored
+		}
+		return path.Join(modulePath, rel)
 	}
+	return ""
+}
 
-	if receiverType != "" {
-		return fmt.Sprintf("%s.%s.%s", modulePath, receiverType, name)
+// modulePath returns the module path declared by dir/go.mod, or "" when
+// there is none.
+func (a *GoAnalyzer) modulePath(dir string) string {
+	if modulePath, ok := a.modulePaths[dir]; ok {
+		return modulePath
 	}
-	return fmt.Sprintf("%s.%s", modulePath, name)
+	modulePath := ""
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		modulePath = modfile.ModulePath(data)
+	}
+	a.modulePaths[dir] = modulePath
+	return modulePath
+}
+
+// slashPath converts both the OS separator and backslashes to forward
+// slashes, so paths and IDs built from them look the same on every OS.
+func slashPath(path string) string {
+	path = strings.ReplaceAll(path, string(os.PathSeparator), "/")
+	return strings.ReplaceAll(path, `\`, "/")
 }
 
 func (a *GoAnalyzer) getComponentIDForPos(pos token.Pos, name string, receiverType string) string {
@@ -188,6 +889,10 @@ func (a *GoAnalyzer) getComponentIDForPos(pos token.Pos, name string, receiverTy
 	return a.getComponentIDForFile(filename, name, receiverType)
 }
 
+// isPosInRepo reports whether pos is in a file under the repo root. It
+// checks the repo rather than the module being loaded, so a callee in
+// another of the repo's modules counts as repo-local: every load shares
+// FileSet, and its positions carry the callee's real file name.
 func (a *GoAnalyzer) isPosInRepo(pos token.Pos) bool {
 	if pos == token.NoPos || a.FileSet == nil {
 		return false
@@ -213,44 +918,224 @@ func isPathInRepo(repoAbs string, path string) bool {
 }
 
 func (a *GoAnalyzer) collectNodes(filePath string, info *fileInfo) {
+	if a.CollectImports {
+		a.collectImports(filePath, info)
+	}
+
+	defer func() {
+		if err := info.source.close(); err != nil {
+			relativePath, _ := filepath.Rel(a.RepoAbs, filePath)
+			a.Diagnostics = append(a.Diagnostics, fmt.Sprintf("could not read the source of %s: %v", slashPath(relativePath), err))
+		}
+	}()
+
+	first := len(a.Nodes)
 	ast.Inspect(info.file, func(n ast.Node) bool {
 		switch x := n.(type) {
 		case *ast.GenDecl:
-			if x.Tok == token.TYPE {
-				for _, spec := range x.Specs {
-					if ts, ok := spec.(*ast.TypeSpec); ok {
-						a.visitTypeSpec(ts, x.Doc, filePath, info.content)
+			a.visitTypeDecl(x, "", filePath, info)
+		case *ast.FuncDecl:
+			componentID := a.visitFuncDecl(x, filePath, info.source, info.info)
+			if isTestFile(filePath) {
+				a.Nodes[a.nodeIndex[componentID]].TestKind = testKind(x, info.file)
+			}
+			if a.LineComments {
+				a.setLineComment(componentID, info.file.Comments, x.End())
+			}
+			// Types declared in a function body are only emitted on
+			// request, under IDs scoped to the function.
+			if a.ExtractLocalTypes && x.Body != nil {
+				ast.Inspect(x.Body, func(n ast.Node) bool {
+					if gen, ok := n.(*ast.GenDecl); ok {
+						a.visitTypeDecl(gen, componentID, filePath, info)
 					}
-				}
+					return true
+				})
 			}
-		case *ast.FuncDecl:
-			a.visitFuncDecl(x, filePath, info.content)
+			return false
 		}
 		return true
 	})
+
+	a.visitVarInitializers(info.file, filePath, info.source)
+
+	for i := first; i < len(a.Nodes); i++ {
+		a.Nodes[i].PackageName = info.pkgName
+		a.Nodes[i].PackagePath = info.pkgPath
+	}
+	if a.CollectPackageDocs && info.file.Doc != nil {
+		a.collectPackageDoc(filePath, info)
+	}
+	if a.CollectGenerate {
+		a.collectGenerate(filePath, info, first)
+	}
+}
+
+// collectPackageDoc records the package doc comment of the file in
+// PackageDocs, under the package's import path or, without one, its
+// directory relative to the repo root. Files are visited in sorted order,
+// so when several files document the package the first one wins.
+func (a *GoAnalyzer) collectPackageDoc(filePath string, info *fileInfo) {
+	key := info.pkgPath
+	if key == "" {
+		dir, _ := filepath.Rel(a.RepoAbs, filepath.Dir(filePath))
+		key = slashPath(dir)
+	}
+	if a.PackageDocs == nil {
+		a.PackageDocs = map[string]string{}
+	}
+	if _, ok := a.PackageDocs[key]; !ok {
+		a.PackageDocs[key] = info.file.Doc.Text()
+	}
+}
+
+// collectImports records the file's imports as the name each is referred to
+// by (its alias, or the package name) mapped to the import path. Blank
+// imports bind no name and are skipped.
+func (a *GoAnalyzer) collectImports(filePath string, info *fileInfo) {
+	imports := map[string]string{}
+	for _, spec := range info.file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name, _ := importedNames(spec, importPath, info.info)
+		if name == "_" {
+			continue
+		}
+		imports[name] = importPath
+	}
+
+	if a.Imports == nil {
+		a.Imports = map[string]map[string]string{}
+	}
+	relativePath, _ := filepath.Rel(a.RepoAbs, filePath)
+	if a.SlashPaths {
+		relativePath = slashPath(relativePath)
+	}
+	a.Imports[relativePath] = imports
+}
+
+// importedNames returns the name an import binds in its file and the name
+// of the imported package; they differ for aliased imports. Without type
+// information the package name is guessed from the import path.
+func importedNames(spec *ast.ImportSpec, importPath string, typeInfo *types.Info) (name, pkgName string) {
+	if typeInfo != nil && typeInfo.PkgNameOf(spec) != nil {
+		pkgName = typeInfo.PkgNameOf(spec).Imported().Name()
+	} else {
+		pkgName = assumedPackageName(importPath)
+	}
+	if spec.Name != nil {
+		return spec.Name.Name, pkgName
+	}
+	return pkgName, pkgName
+}
+
+// assumedPackageName guesses the name of the package at importPath the way
+// goimports does: the last element, skipping a major version suffix such as
+// /v2, without a "go-" prefix and cut at the first character that cannot
+// appear in an identifier, so gopkg.in/yaml.v3 gives yaml.
+func assumedPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if len(base) > 1 && base[0] == 'v' && strings.Trim(base[1:], "0123456789") == "" {
+		base = path.Base(path.Dir(importPath))
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// fileImportNames maps the names the file's imports bind to the names of
+// the imported packages, for naming calls resolved without types.
+func fileImportNames(info *fileInfo) map[string]string {
+	names := map[string]string{}
+	for _, spec := range info.file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name, pkgName := importedNames(spec, importPath, info.info)
+		if name != "_" && name != "." {
+			names[name] = pkgName
+		}
+	}
+	return names
 }
 
 func (a *GoAnalyzer) collectCalls(filePath string, info *fileInfo) {
+	a.importNames[filePath] = fileImportNames(info)
 	ast.Inspect(info.file, func(n ast.Node) bool {
-		if fn, ok := n.(*ast.FuncDecl); ok {
-			a.visitFuncBodyForCalls(fn, filePath, info.info, info.pkg)
+		switch x := n.(type) {
+		case *ast.FuncDecl:
+			a.visitFuncBodyForCalls(x, filePath, info.info, info.pkg)
+		case *ast.TypeSpec:
+			a.collectFields(x, filePath, info.info)
 		}
 		return true
 	})
+
+	a.collectVarInitializerCalls(filePath, info)
+}
+
+// visitTypeDecl visits the type specs of a type declaration. enclosingID is
+// the ID of the function a local declaration appears in, or "".
+func (a *GoAnalyzer) visitTypeDecl(gen *ast.GenDecl, enclosingID string, filePath string, info *fileInfo) {
+	if gen.Tok != token.TYPE {
+		return
+	}
+	// The block's comment documents its only type; in a grouped
+	// block each spec relies on its own doc comment.
+	var genDeclDoc *ast.CommentGroup
+	if len(gen.Specs) == 1 {
+		genDeclDoc = gen.Doc
+	}
+	for _, spec := range gen.Specs {
+		if ts, ok := spec.(*ast.TypeSpec); ok {
+			componentID := a.visitTypeSpec(ts, genDeclDoc, enclosingID, filePath, info.source, info.info)
+			if componentID != "" && a.LineComments {
+				a.setLineComment(componentID, info.file.Comments, ts.End())
+			}
+		}
+	}
+}
+
+// setLineComment sets LineComment on the node with componentID to the text
+// of the comment group starting after end on end's line. The groups are
+// searched by position: ast.CommentMap would attach a trailing comment at
+// the end of the file to the File instead of the declaration.
+func (a *GoAnalyzer) setLineComment(componentID string, comments []*ast.CommentGroup, end token.Pos) {
+	i, ok := a.nodeIndex[componentID]
+	if !ok {
+		return
+	}
+	j := sort.Search(len(comments), func(j int) bool { return comments[j].Pos() >= end })
+	if j < len(comments) && a.FileSet.Position(comments[j].Pos()).Line == a.FileSet.Position(end).Line {
+		a.Nodes[i].LineComment = strings.TrimSpace(comments[j].Text())
+	}
 }
 
-func (a *GoAnalyzer) visitTypeSpec(ts *ast.TypeSpec, genDeclDoc *ast.CommentGroup, filePath string, content []byte) {
-	nodeType := "struct"
-	if _, ok := ts.Type.(*ast.InterfaceType); ok {
+// visitTypeSpec emits a node for a struct or interface type and returns its
+// ID, or "" for other types.
+func (a *GoAnalyzer) visitTypeSpec(ts *ast.TypeSpec, genDeclDoc *ast.CommentGroup, enclosingID string, filePath string, source *fileSource, typeInfo *types.Info) string {
+	var nodeType string
+	switch ts.Type.(type) {
+	case *ast.InterfaceType:
 		nodeType = "interface"
-	} else if _, ok := ts.Type.(*ast.StructType); ok {
+	case *ast.StructType:
 		nodeType = "struct"
-	} else {
-		return // Skip other types for now
+	default:
+		return "" // Skip other types for now
 	}
 
 	relativePath, _ := filepath.Rel(a.RepoAbs, filePath)
 	componentID := a.getComponentIDForFile(filePath, ts.Name.Name, "")
+	if enclosingID != "" {
+		componentID = a.localTypeID(ts, enclosingID)
+	}
 
 	startPos := a.FileSet.Position(ts.Pos())
 	endPos := a.FileSet.Position(ts.End())
@@ -262,17 +1147,15 @@ func (a *GoAnalyzer) visitTypeSpec(ts *ast.TypeSpec, genDeclDoc *ast.CommentGrou
 	}
 
 	// Capture source code
-	var startOffset int
-	if doc != nil {
-		startOffset = a.FileSet.Position(doc.Pos()).Offset
-	} else {
-		startOffset = startPos.Offset
-	}
+	startOffset := a.docStart(doc, startPos)
 	endOffset := endPos.Offset
 
-	var sourceCode string
-	if startOffset >= 0 && endOffset <= len(content) && startOffset <= endOffset {
-		sourceCode = string(content[startOffset:endOffset])
+	var sourceCode, hash string
+	if src, ok := a.nodeSpan(source, filePath, componentID, startOffset, endOffset); ok {
+		hash = contentHash(src)
+		if a.IncludeSource {
+			sourceCode = string(src)
+		}
 	}
 
 	node := models.Node{
@@ -283,11 +1166,19 @@ func (a *GoAnalyzer) visitTypeSpec(ts *ast.TypeSpec, genDeclDoc *ast.CommentGrou
 		RelativePath:  relativePath,
 		StartLine:     startPos.Line,
 		EndLine:       endPos.Line,
+		StartCol:      startPos.Column,
+		EndCol:        endPos.Column,
+		StartByte:     startOffset,
+		EndByte:       endOffset,
 		NodeType:      nodeType,
 		ComponentID:   componentID,
 		DisplayName:   fmt.Sprintf("%s %s", nodeType, ts.Name.Name),
 		DependsOn:     []string{},
 		SourceCode:    sourceCode,
+		ContentHash:   hash,
+		IsInterface:   nodeType == "interface",
+		Exported:      ts.Name.IsExported() && enclosingID == "",
+		EnclosingFunc: enclosingID,
 	}
 
 	if doc != nil {
@@ -295,16 +1186,144 @@ func (a *GoAnalyzer) visitTypeSpec(ts *ast.TypeSpec, genDeclDoc *ast.CommentGrou
 		node.Docstring = doc.Text()
 	}
 
-	a.CollectedNodeIDs[componentID] = true
+	if node.IsInterface {
+		node.InterfaceMethods = a.interfaceMethods(ts, componentID, typeInfo)
+	}
+	if st, ok := ts.Type.(*ast.StructType); ok {
+		node.BaseClasses = embeddedTypes(st)
+		node.Fields = structFields(st)
+	}
+
+	a.addNode(node)
+	return componentID
+}
+
+// addNode records a collected node so that relationships can resolve to it.
+func (a *GoAnalyzer) addNode(node models.Node) {
+	if a.SlashPaths {
+		node.FilePath = slashPath(node.FilePath)
+		node.RelativePath = slashPath(node.RelativePath)
+	}
+	if a.MaxSourceBytes > 0 && len(node.SourceCode) > a.MaxSourceBytes && !node.SourceTruncated {
+		node.SourceCode = truncateSource(node.SourceCode, a.MaxSourceBytes)
+		node.SourceTruncated = true
+	}
+	if node.HasDocstring {
+		node.DeprecationNote, node.Deprecated = deprecationNote(node.Docstring)
+	}
+	a.CollectedNodeIDs[node.ID] = true
+	a.nodeIndex[node.ID] = len(a.Nodes)
 	a.Nodes = append(a.Nodes, node)
 }
 
+// sourceTruncatedMarker ends source code cut short by MaxSourceBytes.
+const sourceTruncatedMarker = "\n// ... truncated"
+
+// truncateSource cuts src to at most max bytes without splitting a UTF-8
+// sequence and appends sourceTruncatedMarker.
+func truncateSource(src string, max int) string {
+	for max > 0 && !utf8.RuneStart(src[max]) {
+		max--
+	}
+	return src[:max] + sourceTruncatedMarker
+}
+
+// funcReceiver returns the receiver type of fn as written, e.g.
+// "*Stack[T]", and the receiver's name, or "" for either when fn has none.
+// Go allows a single receiver, so only the first field of a malformed list
+// is used; an unnamed or blank receiver, as in func (*T) M(), has no name,
+// which keeps calls from being mistaken for calls on the receiver.
+func funcReceiver(fn *ast.FuncDecl) (typ, name string) {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return "", ""
+	}
+	field := fn.Recv.List[0]
+	typ = typeToString(field.Type)
+	if len(field.Names) > 0 && field.Names[0].Name != "_" {
+		name = field.Names[0].Name
+	}
+	return typ, name
+}
+
+// receiverBaseName strips the pointer, parentheses and any type parameters
+// from a receiver type string, so "*Stack[T]" and "*(Stack[T])" name the
+// same type as "Stack".
+func receiverBaseName(typeStr string) string {
+	typeStr = strings.TrimLeft(typeStr, "*(")
+	if i := strings.IndexAny(typeStr, "[)"); i >= 0 {
+		typeStr = typeStr[:i]
+	}
+	return typeStr
+}
+
+// methodTypeName returns the name of the type method fn is declared on, as
+// used in its ID: from the type checker when there is type information, so
+// that it matches the name of the type's node also for a receiver written
+// through an alias, and otherwise from the receiver as written.
+func methodTypeName(fn *ast.FuncDecl, typeInfo *types.Info) string {
+	if typeInfo != nil {
+		if obj, ok := typeInfo.Defs[fn.Name].(*types.Func); ok {
+			if name := receiverTypeString(obj.Type()); name != "" {
+				return name
+			}
+		}
+	}
+	receiver, _ := funcReceiver(fn)
+	return receiverBaseName(receiver)
+}
+
+// embeddedTypes returns the embedded field types of st as written, in
+// declaration order and without pointers: "Base", "io.Reader", "List[T]".
+func embeddedTypes(st *ast.StructType) []string {
+	var names []string
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			names = append(names, strings.TrimPrefix(typeToString(field.Type), "*"))
+		}
+	}
+	return names
+}
+
+// structFields lists the fields of st with their types as written and
+// their doc and trailing comments.
+func structFields(st *ast.StructType) []models.FieldInfo {
+	var fields []models.FieldInfo
+	for _, field := range st.Fields.List {
+		info := models.FieldInfo{Type: typeToString(field.Type)}
+		if field.Doc != nil {
+			info.Doc = field.Doc.Text()
+		}
+		if field.Comment != nil {
+			info.Comment = strings.TrimSpace(field.Comment.Text())
+		}
+		if len(field.Names) == 0 {
+			// An embedded field is named after its type, without the
+			// pointer, package or type arguments.
+			name := receiverBaseName(info.Type)
+			info.Name = name[strings.LastIndexByte(name, '.')+1:]
+			info.Embedded = true
+			fields = append(fields, info)
+			continue
+		}
+		for _, name := range field.Names {
+			info.Name = name.Name
+			fields = append(fields, info)
+		}
+	}
+	return fields
+}
+
+// typeToString renders a type expression as written, e.g. "*pkg.T",
+// "map[string][]int" or "func(int) error", or "" for an expression it does
+// not recognize as a type.
 func typeToString(expr ast.Expr) string {
 	switch t := expr.(type) {
 	case *ast.Ident:
 		return t.Name
 	case *ast.StarExpr:
 		return "*" + typeToString(t.X)
+	case *ast.ParenExpr:
+		return "(" + typeToString(t.X) + ")"
 	case *ast.SelectorExpr:
 		return typeToString(t.X) + "." + t.Sel.Name
 	case *ast.IndexExpr: // Generic[T]
@@ -320,36 +1339,92 @@ func typeToString(expr ast.Expr) string {
 			indices += typeToString(idx)
 		}
 		return typeToString(t.X) + "[" + indices + "]"
+	case *ast.ArrayType:
+		switch t.Len.(type) {
+		case nil:
+			return "[]" + typeToString(t.Elt)
+		case *ast.Ellipsis:
+			return "[...]" + typeToString(t.Elt)
+		default:
+			return "[" + types.ExprString(t.Len) + "]" + typeToString(t.Elt)
+		}
+	case *ast.MapType:
+		return "map[" + typeToString(t.Key) + "]" + typeToString(t.Value)
+	case *ast.ChanType:
+		switch t.Dir {
+		case ast.SEND:
+			return "chan<- " + typeToString(t.Value)
+		case ast.RECV:
+			return "<-chan " + typeToString(t.Value)
+		default:
+			return "chan " + typeToString(t.Value)
+		}
+	case *ast.Ellipsis: // Variadic parameter
+		return "..." + typeToString(t.Elt)
+	case *ast.FuncType:
+		s := "func(" + strings.Join(fieldTypes(t.Params), ", ") + ")"
+		results := fieldTypes(t.Results)
+		switch {
+		case len(results) == 1:
+			s += " " + results[0]
+		case len(results) > 0:
+			s += " (" + strings.Join(results, ", ") + ")"
+		}
+		return s
+	// Anonymous structs and interfaces are abbreviated, as their full
+	// listing can span many lines.
+	case *ast.StructType:
+		if t.Fields == nil || len(t.Fields.List) == 0 {
+			return "struct{}"
+		}
+		return "struct{...}"
+	case *ast.InterfaceType:
+		if t.Methods == nil || len(t.Methods.List) == 0 {
+			return "interface{}"
+		}
+		return "interface{...}"
 	default:
 		return ""
 	}
 }
 
-func (a *GoAnalyzer) visitFuncDecl(fn *ast.FuncDecl, filePath string, content []byte) {
+// fieldTypes renders the type of each entry of fields, once per name, so
+// "a, b int" gives ["int", "int"].
+func fieldTypes(fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var list []string
+	for _, field := range fields.List {
+		for range max(len(field.Names), 1) {
+			list = append(list, typeToString(field.Type))
+		}
+	}
+	return list
+}
+
+func (a *GoAnalyzer) visitFuncDecl(fn *ast.FuncDecl, filePath string, source *fileSource, typeInfo *types.Info) string {
 	relativePath, _ := filepath.Rel(a.RepoAbs, filePath)
 	startPos := a.FileSet.Position(fn.Pos())
 	endPos := a.FileSet.Position(fn.End())
 
 	var componentID string
 	var className string
+	var receiver string
+	var ownerTypeID string
 	var displayName string
 
 	if fn.Recv != nil {
 		// It's a method
-		recvType := ""
-		for _, field := range fn.Recv.List {
-			// FIXED: Use improved typeToString to handle *pkg.Type, Generic[T], etc.
-			typeStr := typeToString(field.Type)
-			// Strip pointer for class name grouping if it's a pointer receiver
-			if len(typeStr) > 0 && typeStr[0] == '*' {
-				recvType = typeStr[1:]
-			} else {
-				recvType = typeStr
-			}
-		}
+		receiver, _ = funcReceiver(fn)
+		recvType := methodTypeName(fn, typeInfo)
 		className = recvType
+		ownerTypeID = a.ownerTypeID(fn, typeInfo)
 		componentID = a.getComponentIDForFile(filePath, fn.Name.Name, recvType)
 		displayName = fmt.Sprintf("method %s.%s", recvType, fn.Name.Name)
+	} else if isInitFunc(fn) {
+		componentID = a.initFuncID(fn, filePath)
+		displayName = "func init"
 	} else {
 		// Regular function
 		componentID = a.getComponentIDForFile(filePath, fn.Name.Name, "")
@@ -365,15 +1440,15 @@ func (a *GoAnalyzer) visitFuncDecl(fn *ast.FuncDecl, filePath string, content []
 	}
 
 	// Capture source code
-	startOffset := startPos.Offset
-	if fn.Doc != nil {
-		startOffset = a.FileSet.Position(fn.Doc.Pos()).Offset
-	}
+	startOffset := a.docStart(fn.Doc, startPos)
 	endOffset := endPos.Offset
 
-	var sourceCode string
-	if startOffset >= 0 && endOffset <= len(content) && startOffset <= endOffset {
-		sourceCode = string(content[startOffset:endOffset])
+	var sourceCode, hash string
+	if src, ok := a.nodeSpan(source, filePath, componentID, startOffset, endOffset); ok {
+		hash = contentHash(src)
+		if a.IncludeSource {
+			sourceCode = string(src)
+		}
 	}
 
 	node := models.Node{
@@ -384,12 +1459,21 @@ func (a *GoAnalyzer) visitFuncDecl(fn *ast.FuncDecl, filePath string, content []
 		RelativePath:  relativePath,
 		StartLine:     startPos.Line,
 		EndLine:       endPos.Line,
+		StartCol:      startPos.Column,
+		EndCol:        endPos.Column,
+		StartByte:     startOffset,
+		EndByte:       endOffset,
 		NodeType:      nodeType,
 		ComponentID:   componentID,
 		ClassName:     className,
-		DisplayName:   displayName,
+		ReceiverType:  receiver,
+		OwnerTypeID:   ownerTypeID,
+		DisplayName:   funcDisplayName(fn, typeInfo),
+		ShortName:     displayName,
 		DependsOn:     []string{},
 		SourceCode:    sourceCode,
+		ContentHash:   hash,
+		Exported:      fn.Name.IsExported(),
 	}
 
 	if fn.Doc != nil {
@@ -397,49 +1481,127 @@ func (a *GoAnalyzer) visitFuncDecl(fn *ast.FuncDecl, filePath string, content []
 		node.Docstring = fn.Doc.Text()
 	}
 
-	// Extract parameters
+	node.Parameters = paramNames(fn.Type)
+
+	a.addNode(node)
+
+	if a.ExtractClosures && fn.Body != nil {
+		a.collectClosures(componentID, filePath, source, fn.Body)
+	}
+	return componentID
+}
+
+// funcDisplayName renders fn's full signature, e.g.
+// "func Name(a int, b string) (err error)" or "method (r *T) Name() error".
+// Types come from the type checker when available, qualified by package
+// name outside fn's own package, and from the source otherwise.
+func funcDisplayName(fn *ast.FuncDecl, typeInfo *types.Info) string {
+	kind := "func"
+	if fn.Recv != nil {
+		kind = "method"
+	}
+
+	if typeInfo != nil {
+		if obj, ok := typeInfo.Defs[fn.Name].(*types.Func); ok {
+			qualifier := func(pkg *types.Package) string {
+				if pkg == obj.Pkg() {
+					return ""
+				}
+				return pkg.Name()
+			}
+			sig := obj.Type().(*types.Signature)
+			receiver := ""
+			if recv := sig.Recv(); recv != nil {
+				receiver = "(" + strings.TrimSpace(recv.Name()+" "+types.TypeString(recv.Type(), qualifier)) + ") "
+			}
+			return fmt.Sprintf("%s %s%s%s", kind, receiver, fn.Name.Name, strings.TrimPrefix(types.TypeString(sig, qualifier), "func"))
+		}
+	}
+
+	receiver := ""
+	if fn.Recv != nil {
+		receiver = fieldListString(fn.Recv) + " "
+	}
+	results := ""
+	if fn.Type.Results != nil && len(fn.Type.Results.List) > 0 {
+		results = " " + fieldListString(fn.Type.Results)
+		if len(fn.Type.Results.List) == 1 && len(fn.Type.Results.List[0].Names) == 0 {
+			results = " " + types.ExprString(fn.Type.Results.List[0].Type)
+		}
+	}
+	return fmt.Sprintf("%s %s%s%s%s", kind, receiver, fn.Name.Name, fieldListString(fn.Type.Params), results)
+}
+
+// fieldListString renders a parameter, result or receiver list as written,
+// e.g. "(a, b int, opts ...Option)".
+func fieldListString(list *ast.FieldList) string {
+	var fields []string
+	if list != nil {
+		for _, field := range list.List {
+			names := make([]string, len(field.Names))
+			for i, name := range field.Names {
+				names[i] = name.Name
+			}
+			fields = append(fields, strings.TrimSpace(strings.Join(names, ", ")+" "+types.ExprString(field.Type)))
+		}
+	}
+	return "(" + strings.Join(fields, ", ") + ")"
+}
+
+// paramNames returns the declared parameter names of a function type.
+func paramNames(ft *ast.FuncType) []string {
 	params := []string{}
-	if fn.Type.Params != nil {
-		for _, p := range fn.Type.Params.List {
+	if ft.Params != nil {
+		for _, p := range ft.Params.List {
 			for _, name := range p.Names {
 				params = append(params, name.Name)
 			}
 		}
 	}
-	node.Parameters = params
-
-	a.CollectedNodeIDs[componentID] = true
-	a.Nodes = append(a.Nodes, node)
+	return params
 }
 
 func (a *GoAnalyzer) visitFuncBodyForCalls(fn *ast.FuncDecl, filePath string, typeInfo *types.Info, typePkg *types.Package) {
-	if fn.Body == nil {
-		return
-	}
-
 	callerID := ""
 	recvName := ""
 	recvType := ""
 	if fn.Recv != nil {
-		for _, field := range fn.Recv.List {
-			typeStr := typeToString(field.Type)
-			if len(typeStr) > 0 && typeStr[0] == '*' {
-				recvType = typeStr[1:]
-			} else {
-				recvType = typeStr
-			}
-			if len(field.Names) > 0 {
-				recvName = field.Names[0].Name
-			}
-		}
+		_, recvName = funcReceiver(fn)
+		recvType = methodTypeName(fn, typeInfo)
 		callerID = a.getComponentIDForFile(filePath, fn.Name.Name, recvType)
+	} else if id, ok := a.initIDs[fn]; ok {
+		callerID = id
 	} else {
 		callerID = a.getComponentIDForFile(filePath, fn.Name.Name, "")
 	}
 
-	ast.Inspect(fn.Body, func(n ast.Node) bool {
-		if call, ok := n.(*ast.CallExpr); ok {
-			a.processCall(callerID, recvName, recvType, call, typeInfo, typePkg, filePath)
+	a.collectReturns(callerID, fn, typeInfo)
+
+	if fn.Body == nil {
+		return
+	}
+
+	a.walkCalls(fn.Body, callerID, recvName, recvType, filePath, typeInfo, typePkg)
+
+	a.collectProduces(callerID, fn.Body, filePath, typeInfo)
+	a.collectAsserts(callerID, fn.Body, typeInfo)
+}
+
+// walkCalls records the calls made in body. Calls inside an extracted
+// function literal are attributed to the literal's closure node instead.
+func (a *GoAnalyzer) walkCalls(body ast.Node, callerID string, recvName string, recvType string, filePath string, typeInfo *types.Info, typePkg *types.Package) {
+	if !a.emits("calls") && !a.emits("converts") && !(a.ResolveInterfaceDispatch && a.emits("dynamic_call")) {
+		return
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.FuncLit:
+			if closureID, ok := a.closureIDs[x]; ok {
+				a.walkCalls(x.Body, closureID, recvName, recvType, filePath, typeInfo, typePkg)
+				return false
+			}
+		case *ast.CallExpr:
+			a.processCall(callerID, recvName, recvType, x, typeInfo, typePkg, filePath)
 		}
 		return true
 	})
@@ -447,16 +1609,19 @@ func (a *GoAnalyzer) visitFuncBodyForCalls(fn *ast.FuncDecl, filePath string, ty
 
 func (a *GoAnalyzer) processCall(callerID string, recvName string, recvType string, call *ast.CallExpr, typeInfo *types.Info, typePkg *types.Package, filePath string) {
 	if typeInfo != nil && typePkg != nil {
-		if calleeName, resolved, ok := a.resolveCallWithTypes(call, typeInfo, typePkg); ok {
-			if calleeName != "" {
-				rel := models.CallRelationship{
-					Caller:           callerID,
-					Callee:           calleeName,
-					CallLine:         a.FileSet.Position(call.Pos()).Line,
-					RelationshipType: "calls",
-					IsResolved:       resolved,
-				}
-				a.Relationships = append(a.Relationships, rel)
+		if a.collectConversion(callerID, call, typeInfo) {
+			return
+		}
+		if rel, ok := a.resolveCallWithTypes(call, typeInfo, typePkg); ok {
+			if rel.Callee != "" {
+				rel.Caller = callerID
+				rel.CallLine = a.FileSet.Position(call.Pos()).Line
+				rel.RelationshipType = "calls"
+				rel.IsStdlib = a.MarkStdlib && a.isStdlibCall(call, typeInfo)
+				a.addCall(rel)
+			}
+			if a.ResolveInterfaceDispatch {
+				a.collectDynamicCalls(callerID, call, typeInfo)
 			}
 			return
 		}
@@ -464,7 +1629,7 @@ func (a *GoAnalyzer) processCall(callerID string, recvName string, recvType stri
 
 	var calleeName string
 
-	switch fun := call.Fun.(type) {
+	switch fun := ast.Unparen(call.Fun).(type) {
 	case *ast.Ident:
 		// Simple call: funcName()
 		// We capture just the name. The ID ambiguity remains for now (could be local or builtin),
@@ -488,80 +1653,227 @@ func (a *GoAnalyzer) processCall(callerID string, recvName string, recvType stri
 			// If this is a call on the current method receiver, resolve to method ID.
 			if recvName != "" && recvType != "" && xIdent.Name == recvName {
 				calleeName = a.getComponentIDForFile(filePath, fun.Sel.Name, recvType)
+			} else if pkgName, ok := a.importNames[filePath][xIdent.Name]; ok {
+				// Name package calls after the package, not its alias,
+				// as the type-aware path does.
+				calleeName = fmt.Sprintf("%s.%s", pkgName, fun.Sel.Name)
 			} else {
 				calleeName = fmt.Sprintf("%s.%s", xIdent.Name, fun.Sel.Name)
 			}
+		} else {
+			switch fun.X.(type) {
+			case *ast.IndexExpr, *ast.SelectorExpr:
+				// Method on an element or a field chain: s[i].Method(),
+				// a.b.c.Method(). The receiver type is unknown without
+				// types, so only the method name is recorded.
+				calleeName = fun.Sel.Name
+			}
 		}
 	}
 
 	if calleeName != "" {
-		rel := models.CallRelationship{
+		a.addCall(models.CallRelationship{
 			Caller:           callerID,
 			Callee:           calleeName,
 			CallLine:         a.FileSet.Position(call.Pos()).Line,
 			RelationshipType: "calls",
 			IsResolved:       a.CollectedNodeIDs[calleeName],
+			Confidence:       models.ConfidenceHeuristic,
+		})
+	}
+}
+
+// addCall records a call relationship. A resolved call from a function to
+// itself is recorded as "recurses" and flags the function node.
+func (a *GoAnalyzer) addCall(rel models.CallRelationship) {
+	if !a.emits("calls") {
+		return
+	}
+	if rel.IsResolved && rel.Callee == rel.Caller {
+		rel.RelationshipType = "recurses"
+		if i, ok := a.nodeIndex[rel.Caller]; ok {
+			a.Nodes[i].IsRecursive = true
 		}
-		a.Relationships = append(a.Relationships, rel)
 	}
+	a.Relationships = append(a.Relationships, rel)
 }
 
-func (a *GoAnalyzer) resolveCallWithTypes(call *ast.CallExpr, typeInfo *types.Info, typePkg *types.Package) (string, bool, bool) {
-	switch fun := call.Fun.(type) {
+// isStdlibCall reports whether call invokes a function or method from the
+// standard library. Standard library import paths have no dot in their
+// first element, unlike module paths such as github.com/...; repo-local
+// packages are excluded in case the repo's own module path has no dot.
+func (a *GoAnalyzer) isStdlibCall(call *ast.CallExpr, typeInfo *types.Info) bool {
+	var obj types.Object
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		obj = typeInfo.Uses[fun]
+	case *ast.SelectorExpr:
+		if sel := typeInfo.Selections[fun]; sel != nil {
+			obj = sel.Obj()
+		} else {
+			obj = typeInfo.Uses[fun.Sel]
+		}
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok || fn.Pkg() == nil || a.isPosInRepo(fn.Pos()) {
+		return false
+	}
+	first, _, _ := strings.Cut(fn.Pkg().Path(), "/")
+	return !strings.Contains(first, ".")
+}
+
+// resolveCallWithTypes names the callee of call using type information and
+// returns the callee half of its relationship (Callee, IsResolved and
+// Confidence). ok is false when the types do not identify the callee, and
+// the syntactic fallback applies; an empty Callee with ok means no edge.
+func (a *GoAnalyzer) resolveCallWithTypes(call *ast.CallExpr, typeInfo *types.Info, typePkg *types.Package) (models.CallRelationship, bool) {
+	switch fun := ast.Unparen(call.Fun).(type) {
 	case *ast.Ident:
 		obj := typeInfo.Uses[fun]
 		switch fn := obj.(type) {
 		case *types.Func:
-			calleeName := a.getComponentIDForPos(fn.Pos(), fn.Name(), "")
-			if calleeName != "" && a.isPosInRepo(fn.Pos()) {
-				return calleeName, a.CollectedNodeIDs[calleeName], true
-			}
-			if fn.Pkg() != nil {
-				return fmt.Sprintf("%s.%s", fn.Pkg().Name(), fn.Name()), false, true
-			}
-			return fn.Name(), false, true
+			return a.funcCallee(fn), true
 		case *types.Builtin:
-			return fun.Name, false, true
+			return qualifiedCallee(fun.Name), true
+		case *types.Var:
+			return a.funcAliasCallee(fn)
 		default:
-			return "", false, false
+			return models.CallRelationship{}, false
 		}
 
 	case *ast.SelectorExpr:
 		if sel := typeInfo.Selections[fun]; sel != nil {
+			// For a method promoted through embedding, sel.Obj() is the
+			// embedded type's method, so its position and receiver give the
+			// defining package's node even when that differs from the
+			// package of the selector's operand.
 			if fn, ok := sel.Obj().(*types.Func); ok {
+				if tp, ok := sel.Recv().(*types.TypeParam); ok {
+					calleeName := a.typeParamCallee(fn, tp, typePkg)
+					return models.CallRelationship{
+						Callee:     calleeName,
+						IsResolved: a.CollectedNodeIDs[calleeName],
+						Confidence: models.ConfidenceHeuristic,
+					}, true
+				}
 				recvType := receiverTypeString(fn.Type())
 				calleeName := a.getComponentIDForPos(fn.Pos(), fn.Name(), recvType)
 				if calleeName != "" && a.isPosInRepo(fn.Pos()) {
-					return calleeName, a.CollectedNodeIDs[calleeName], true
+					return a.exactCallee(calleeName), true
 				}
-				// External method call on a value; fall back to a type-qualified name.
-				recvStr := types.TypeString(sel.Recv(), func(pkg *types.Package) string {
+				// External method; fall back to a type-qualified name. A
+				// method expression such as (*pkg.T).M names the type, not a
+				// value, so the pointer is dropped to match the method's owner.
+				recv := sel.Recv()
+				if ptr, ok := recv.(*types.Pointer); ok && sel.Kind() == types.MethodExpr {
+					recv = ptr.Elem()
+				}
+				recvStr := types.TypeString(recv, func(pkg *types.Package) string {
 					if pkg == typePkg {
 						return ""
 					}
 					return pkg.Name()
 				})
-				return fmt.Sprintf("%s.%s", recvStr, fn.Name()), false, true
+				return qualifiedCallee(fmt.Sprintf("%s.%s", recvStr, fn.Name())), true
 			}
-			return "", false, false
+			if sel.Kind() == types.FieldVal {
+				// A func-typed field such as s.handler(req): there is no
+				// method node to point at, and the function it holds is only
+				// known at run time. Name the edge after a named field type,
+				// and record nothing for a plain func type.
+				if named, ok := sel.Type().(*types.Named); ok {
+					return models.CallRelationship{
+						Callee: types.TypeString(named, func(pkg *types.Package) string {
+							if pkg == typePkg {
+								return ""
+							}
+							return pkg.Name()
+						}),
+						Confidence: models.ConfidenceHeuristic,
+					}, true
+				}
+				return models.CallRelationship{}, true
+			}
+			return models.CallRelationship{}, false
 		}
 
 		if xIdent, ok := fun.X.(*ast.Ident); ok {
 			if _, ok := typeInfo.Uses[xIdent].(*types.PkgName); ok {
-				if obj := typeInfo.Uses[fun.Sel]; obj != nil {
-					if fn, ok := obj.(*types.Func); ok {
-						calleeName := a.getComponentIDForPos(fn.Pos(), fn.Name(), "")
-						if calleeName != "" && a.isPosInRepo(fn.Pos()) {
-							return calleeName, a.CollectedNodeIDs[calleeName], true
-						}
-						return fmt.Sprintf("%s.%s", xIdent.Name, fn.Name()), false, true
-					}
+				switch obj := typeInfo.Uses[fun.Sel].(type) {
+				case *types.Func:
+					return a.funcCallee(obj), true
+				case *types.Var:
+					return a.funcAliasCallee(obj)
 				}
 			}
 		}
 	}
 
-	return "", false, false
+	return models.CallRelationship{}, false
+}
+
+// funcCallee is the callee half of a relationship to fn, a function
+// without a receiver.
+func (a *GoAnalyzer) funcCallee(fn *types.Func) models.CallRelationship {
+	calleeName := a.getComponentIDForPos(fn.Pos(), fn.Name(), "")
+	if calleeName != "" && a.isPosInRepo(fn.Pos()) {
+		return a.exactCallee(calleeName)
+	}
+	if fn.Pkg() != nil {
+		return qualifiedCallee(fmt.Sprintf("%s.%s", fn.Pkg().Name(), fn.Name()))
+	}
+	return qualifiedCallee(fn.Name())
+}
+
+// funcAliasCallee is the callee half of a call through v, a function-typed
+// package-level variable, to the function v is initialized to (see
+// collectFuncAliases). The variable may be reassigned, so the edge is only
+// heuristic. For other variables, ok is false.
+func (a *GoAnalyzer) funcAliasCallee(v *types.Var) (rel models.CallRelationship, ok bool) {
+	fn, ok := a.funcAliases[v]
+	if !ok {
+		return models.CallRelationship{}, false
+	}
+	rel = a.funcCallee(fn)
+	rel.Confidence = models.ConfidenceHeuristic
+	return rel, true
+}
+
+// exactCallee is the callee half of a relationship to calleeName, the ID of
+// a repo declaration identified by the type checker.
+func (a *GoAnalyzer) exactCallee(calleeName string) models.CallRelationship {
+	return models.CallRelationship{
+		Callee:     calleeName,
+		IsResolved: a.CollectedNodeIDs[calleeName],
+		Confidence: models.ConfidenceExact,
+	}
+}
+
+// qualifiedCallee is the callee half of a relationship to name, a callee
+// outside the repo identified by the type checker.
+func qualifiedCallee(name string) models.CallRelationship {
+	return models.CallRelationship{Callee: name, Confidence: models.ConfidencePackageQualified}
+}
+
+// typeParamCallee names fn, a method called on a value of type parameter
+// tp, after the constraint interface declaring it: by ID when the interface
+// is in the repo (pkg.file.Stringer.String), by qualified name otherwise
+// (fmt.Stringer.String). Methods of an inline constraint such as
+// interface{ Len() int } are named after the type parameter (T.Len).
+func (a *GoAnalyzer) typeParamCallee(fn *types.Func, tp *types.TypeParam, typePkg *types.Package) string {
+	named, ok := fn.Type().(*types.Signature).Recv().Type().(*types.Named)
+	if !ok {
+		return fmt.Sprintf("%s.%s", tp.Obj().Name(), fn.Name())
+	}
+	if a.isPosInRepo(named.Obj().Pos()) {
+		return a.getComponentIDForPos(named.Obj().Pos(), fn.Name(), named.Obj().Name())
+	}
+	return fmt.Sprintf("%s.%s", types.TypeString(named, func(pkg *types.Package) string {
+		if pkg == typePkg {
+			return ""
+		}
+		return pkg.Name()
+	}), fn.Name())
 }
 
 func receiverTypeString(t types.Type) string {
@@ -573,9 +1885,14 @@ func receiverTypeString(t types.Type) string {
 	if recv == nil {
 		return ""
 	}
-	recvType := recv.Type()
+	recvType := types.Unalias(recv.Type())
 	if ptr, ok := recvType.(*types.Pointer); ok {
-		recvType = ptr.Elem()
+		recvType = types.Unalias(ptr.Elem())
+	}
+	// Method IDs use the bare type name, also for instantiated generics
+	// and receivers written through an alias.
+	if named, ok := recvType.(*types.Named); ok {
+		return named.Obj().Name()
 	}
 	return types.TypeString(recvType, func(pkg *types.Package) string { return "" })
 }
@@ -1,13 +1,28 @@
 package analyzer
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
 )
 
-func writeGoMod(t *testing.T, dir string) {
+func writeGoMod(t testing.TB, dir string) {
 	t.Helper()
 	content := "module example.com/test\n\ngo 1.25\n"
 	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(content), 0644); err != nil {
@@ -15,6 +30,39 @@ func writeGoMod(t *testing.T, dir string) {
 	}
 }
 
+// writeFiles writes files (relative path -> content) under dir, creating
+// parent directories as needed.
+func writeFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// analyzeFiles writes files (relative path -> content) into a fresh module
+// and returns the analyzer after a successful Analyze.
+func analyzeFiles(t *testing.T, files map[string]string) *GoAnalyzer {
+	t.Helper()
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	writeFiles(t, tmpDir, files)
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	return analyzer
+}
+
 func TestAnalyzeStructSourceExtraction(t *testing.T) {
 	// 1. Setup temporary test file
 	content := `package testpkg
@@ -278,3 +326,2457 @@ func InternalFunc() {}
 		t.Error("Expected fmt.Println call to NOT be resolved (is_resolved=false)")
 	}
 }
+
+func TestAnalyzeWithoutModule(t *testing.T) {
+	content := `package snippet
+
+type Config struct{}
+
+func Load() {
+	parse()
+}
+
+func parse() {}
+`
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "snippet.go")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, node := range analyzer.Nodes {
+		names[node.Name] = true
+	}
+	for _, want := range []string{"Config", "Load", "parse"} {
+		if !names[want] {
+			t.Errorf("Expected node %s without go.mod, got %v", want, names)
+		}
+	}
+
+	found := false
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "snippet.Load" && rel.Callee == "snippet.parse" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected best-effort call edge snippet.Load -> snippet.parse, got %+v", analyzer.Relationships)
+	}
+}
+
+func TestAnalyzeWithoutModuleDiagnostic(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "snippet.go"), []byte("package snippet\n\nfunc Load() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if len(analyzer.Diagnostics) != 1 || !strings.Contains(analyzer.Diagnostics[0], "no go.mod or go.work found") ||
+		!strings.Contains(analyzer.Diagnostics[0], "syntactic-only") {
+		t.Errorf("Expected a module-less diagnostic, got %v", analyzer.Diagnostics)
+	}
+
+	withModule := analyzeFiles(t, map[string]string{"a.go": "package a\n"})
+	if len(withModule.Diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics inside a module, got %v", withModule.Diagnostics)
+	}
+}
+
+func TestAnalyzeWithoutSource(t *testing.T) {
+	content := `package testpkg
+
+// Greeter says hello.
+type Greeter struct{}
+
+func (g *Greeter) Greet() string {
+	return "hello"
+}
+`
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, "greeter.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	marshal := func(includeSource bool) ([]byte, []models.Node) {
+		analyzer, err := NewGoAnalyzer(tmpDir)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		analyzer.IncludeSource = includeSource
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		output, err := json.Marshal(models.NewAnalysisResult(analyzer.Nodes, analyzer.Relationships))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output, analyzer.Nodes
+	}
+
+	withSource, _ := marshal(true)
+	withoutSource, nodes := marshal(false)
+
+	if len(nodes) == 0 {
+		t.Fatal("Expected nodes without source")
+	}
+	for _, node := range nodes {
+		if node.SourceCode != "" {
+			t.Errorf("Expected empty SourceCode for %s, got %q", node.ID, node.SourceCode)
+		}
+		if node.StartLine == 0 || node.EndLine == 0 {
+			t.Errorf("Expected line span for %s to be kept", node.ID)
+		}
+	}
+	if strings.Contains(string(withoutSource), "source_code") {
+		t.Error("Expected source_code to be omitted from JSON")
+	}
+	if len(withoutSource) >= len(withSource) {
+		t.Errorf("Expected smaller output without source: %d >= %d", len(withoutSource), len(withSource))
+	}
+}
+
+func TestAnalyzeTypeSwitchMethodCalls(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"switch.go": `package testpkg
+
+type Doer interface{ Do() }
+
+type Concrete struct{}
+
+func (c *Concrete) Do() {}
+
+func (c *Concrete) Extra() {}
+
+type Other struct{}
+
+func (o Other) Do() {}
+
+func Dispatch(x Doer) {
+	switch v := x.(type) {
+	case *Concrete:
+		v.Extra()
+	case Other:
+		v.Do()
+	}
+}
+`,
+	})
+
+	callees := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "switch.Dispatch" && rel.RelationshipType == "calls" {
+			if !rel.IsResolved {
+				t.Errorf("Expected narrowed call to %s to be resolved", rel.Callee)
+			}
+			callees[rel.Callee] = true
+		}
+	}
+	for _, want := range []string{"switch.Concrete.Extra", "switch.Other.Do"} {
+		if !callees[want] {
+			t.Errorf("Expected call to %s from type switch case, got %v", want, callees)
+		}
+	}
+}
+
+// expiringContext reports context.DeadlineExceeded once Err has been
+// consulted more than budget times, making deadline tests deterministic.
+type expiringContext struct {
+	context.Context
+	budget int
+}
+
+func (c *expiringContext) Err() error {
+	if c.budget <= 0 {
+		return context.DeadlineExceeded
+	}
+	c.budget--
+	return nil
+}
+
+func TestAnalyzeContextDeadlinePartial(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 10; i++ {
+		files[fmt.Sprintf("file%d.go", i)] = fmt.Sprintf("package testpkg\n\nfunc F%d() {}\n", i)
+	}
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	writeFiles(t, tmpDir, files)
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	fileInfos, err := analyzer.loadFiles(context.Background())
+	if err != nil {
+		t.Fatalf("loadFiles failed: %v", err)
+	}
+
+	// The deadline fires after three files of the node pass.
+	err = analyzer.collect(&expiringContext{Context: context.Background(), budget: 3}, analyzer.analyzedFiles(fileInfos), fileInfos)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected DeadlineExceeded, got %v", err)
+	}
+	if !analyzer.Partial {
+		t.Error("Expected Partial to be true")
+	}
+	if len(analyzer.Nodes) != 3 {
+		t.Errorf("Expected the 3 nodes collected before the deadline, got %d", len(analyzer.Nodes))
+	}
+	if len(analyzer.Diagnostics) == 0 {
+		t.Error("Expected a diagnostic explaining the partial result")
+	}
+}
+
+func TestAnalyzeNodeOffsets(t *testing.T) {
+	content := `package testpkg
+
+// Point is documented.
+type Point struct {
+	X, Y int
+}
+
+type Plain struct{}
+
+// Len returns the length.
+func (p Point) Len() int {
+	return p.X + p.Y
+}
+
+func helper() {}
+`
+	analyzer := analyzeFiles(t, map[string]string{"offsets.go": content})
+
+	if len(analyzer.Nodes) != 4 {
+		t.Fatalf("Expected 4 nodes, got %d", len(analyzer.Nodes))
+	}
+	for _, node := range analyzer.Nodes {
+		if node.StartByte < 0 || node.EndByte > len(content) || node.StartByte > node.EndByte {
+			t.Errorf("Invalid byte span for %s: [%d, %d)", node.ID, node.StartByte, node.EndByte)
+			continue
+		}
+		if got := content[node.StartByte:node.EndByte]; got != node.SourceCode {
+			t.Errorf("Byte span of %s does not match SourceCode.\nSpan:\n%s\nSourceCode:\n%s", node.ID, got, node.SourceCode)
+		}
+		if node.StartCol == 0 || node.EndCol == 0 {
+			t.Errorf("Expected columns for %s, got %d-%d", node.ID, node.StartCol, node.EndCol)
+		}
+	}
+}
+
+func TestAnalyzeProgressFunc(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		content := "package testpkg\n\nfunc " + strings.ToUpper(name[:1]) + "() {}\n"
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	calls := map[string][]int{}
+	analyzer.ProgressFunc = func(stage string, current, total int) {
+		if current > total {
+			t.Errorf("Stage %s reported %d of %d", stage, current, total)
+		}
+		calls[stage] = append(calls[stage], current)
+	}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	want := map[string]int{"load": 1, "nodes": 3, "calls": 3}
+	for stage, count := range want {
+		got := calls[stage]
+		if len(got) != count {
+			t.Errorf("Expected %d %s callbacks, got %v", count, stage, got)
+			continue
+		}
+		for i, current := range got {
+			if current != i+1 {
+				t.Errorf("Expected increasing %s counts, got %v", stage, got)
+				break
+			}
+		}
+	}
+}
+
+func TestAnalyzeRecursion(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"recursion.go": `package testpkg
+
+func Factorial(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return n * Factorial(n-1)
+}
+
+type Tree struct{ children []*Tree }
+
+func (t *Tree) Size() int {
+	size := 1
+	for _, c := range t.children {
+		size += c.Size()
+	}
+	return size
+}
+
+func Plain() int { return Factorial(3) }
+`,
+	})
+
+	recursive := map[string]bool{}
+	for _, node := range analyzer.Nodes {
+		recursive[node.ID] = node.IsRecursive
+	}
+	for id, want := range map[string]bool{
+		"recursion.Factorial": true,
+		"recursion.Tree.Size": true,
+		"recursion.Plain":     false,
+	} {
+		if recursive[id] != want {
+			t.Errorf("Expected IsRecursive=%v for %s, got %v", want, id, recursive[id])
+		}
+	}
+
+	found := false
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "recursion.Factorial" && rel.Callee == "recursion.Factorial" {
+			found = true
+			if rel.RelationshipType != "recurses" {
+				t.Errorf("Expected self edge to be 'recurses', got %q", rel.RelationshipType)
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected a self edge for Factorial")
+	}
+}
+
+func TestAnalyzeDotImports(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"util/util.go": `package util
+
+func Helper() string { return "" }
+`,
+		"caller.go": `package testpkg
+
+import (
+	. "example.com/test/util"
+	. "strings"
+)
+
+func Caller() string {
+	return ToUpper(Helper())
+}
+`,
+	})
+
+	callees := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "caller.Caller" {
+			callees[rel.Callee] = rel.IsResolved
+		}
+	}
+
+	if resolved, ok := callees["util.util.Helper"]; !ok {
+		t.Errorf("Expected dot-imported repo call to util.util.Helper, got %v", callees)
+	} else if !resolved {
+		t.Error("Expected dot-imported repo call to be resolved")
+	}
+	if resolved, ok := callees["strings.ToUpper"]; !ok {
+		t.Errorf("Expected dot-imported stdlib call to strings.ToUpper, got %v", callees)
+	} else if resolved {
+		t.Error("Expected dot-imported stdlib call to be unresolved")
+	}
+}
+
+func TestAnalyzeGroupedTypeDocs(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"group.go": `package testpkg
+
+// Types used by the parser.
+type (
+	// First is documented.
+	First struct{}
+
+	Second struct{}
+)
+
+// Single is documented on its declaration.
+type (
+	Single struct{}
+)
+`,
+	})
+
+	docs := map[string]string{}
+	hasDoc := map[string]bool{}
+	for _, node := range analyzer.Nodes {
+		docs[node.Name] = strings.TrimSpace(node.Docstring)
+		hasDoc[node.Name] = node.HasDocstring
+	}
+
+	if docs["First"] != "First is documented." {
+		t.Errorf("Expected First to keep its own doc, got %q", docs["First"])
+	}
+	if hasDoc["Second"] || docs["Second"] != "" {
+		t.Errorf("Expected Second to have no doc, got %q", docs["Second"])
+	}
+	if docs["Single"] != "Single is documented on its declaration." {
+		t.Errorf("Expected a single-spec block to use the block doc, got %q", docs["Single"])
+	}
+}
+
+func TestAnalyzeCollectImports(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	content := `package testpkg
+
+import (
+	json "encoding/json"
+	"strings"
+	_ "embed"
+)
+
+func Encode(v any) string {
+	data, _ := json.Marshal(v)
+	return strings.TrimSpace(string(data))
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "encode.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.CollectImports = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	imports, ok := analyzer.Imports["encode.go"]
+	if !ok {
+		t.Fatalf("Expected imports for encode.go, got %v", analyzer.Imports)
+	}
+	want := map[string]string{"json": "encoding/json", "strings": "strings"}
+	if len(imports) != len(want) {
+		t.Errorf("Expected imports %v, got %v", want, imports)
+	}
+	for name, path := range want {
+		if imports[name] != path {
+			t.Errorf("Expected %s -> %s, got %q", name, path, imports[name])
+		}
+	}
+}
+
+func TestAnalyzePatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	files := map[string]string{
+		"root.go":    "package testpkg\n\nfunc Root() {}\n",
+		"sub/sub.go": "package sub\n\nfunc Sub() {}\n",
+		"other/o.go": "package other\n\nfunc Other() {}\n",
+	}
+	writeFiles(t, tmpDir, files)
+
+	nodeIDs := func(patterns ...string) []string {
+		analyzer, err := NewGoAnalyzer(tmpDir)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		analyzer.Patterns = patterns
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		ids := []string{}
+		for _, node := range analyzer.Nodes {
+			ids = append(ids, node.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	defaultIDs := nodeIDs()
+	explicitIDs := nodeIDs("./...")
+	if strings.Join(defaultIDs, ",") != strings.Join(explicitIDs, ",") {
+		t.Errorf("Expected ./... to match the default analysis.\nDefault: %v\nExplicit: %v", defaultIDs, explicitIDs)
+	}
+	if len(defaultIDs) != 3 {
+		t.Errorf("Expected 3 nodes by default, got %v", defaultIDs)
+	}
+
+	if got := nodeIDs("./sub"); len(got) != 1 || got[0] != "sub.sub.Sub" {
+		t.Errorf("Expected only sub.sub.Sub for ./sub, got %v", got)
+	}
+}
+
+func TestAnalyzeIDStyles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	files := map[string]string{
+		"main.go": `package main
+
+import "example.com/test/store"
+
+func main() {
+	var s store.Store
+	s.Save()
+}
+`,
+		"store/store.go": "package store\n\ntype Store struct{}\n",
+		"store/save.go":  "package store\n\nfunc (s Store) Save() {}\n",
+	}
+	writeFiles(t, tmpDir, files)
+
+	tests := []struct {
+		style, prefix  string
+		mainID, saveID string
+		storeID        string
+	}{
+		{IDStyleDottedPath, "", "main.main", "store.save.Store.Save", "store.store.Store"},
+		{IDStyleDottedPath, "example.com", "example.com.main.main", "example.com.store.save.Store.Save", "example.com.store.store.Store"},
+		{IDStyleSlashPath, "", "main.main", "store/save.Store.Save", "store/store.Store"},
+		{IDStyleImportPath, "", "example.com/test.main", "example.com/test/store.Store.Save", "example.com/test/store.Store"},
+		{IDStyleImportPath, "example.com/test", "example.com/test.main", "example.com/test/store.Store.Save", "example.com/test/store.Store"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style+"/"+tt.prefix, func(t *testing.T) {
+			analyzer, err := NewGoAnalyzer(tmpDir)
+			if err != nil {
+				t.Fatalf("Failed to create analyzer: %v", err)
+			}
+			analyzer.IDStyle = tt.style
+			analyzer.ModulePrefix = tt.prefix
+			if err := analyzer.Analyze(); err != nil {
+				t.Fatalf("Analyze failed: %v", err)
+			}
+
+			for _, id := range []string{tt.mainID, tt.saveID, tt.storeID} {
+				if !analyzer.CollectedNodeIDs[id] {
+					t.Errorf("Expected node %s, got %v", id, analyzer.CollectedNodeIDs)
+				}
+			}
+			found := false
+			for _, rel := range analyzer.Relationships {
+				if rel.Caller == tt.mainID && rel.Callee == tt.saveID && rel.IsResolved {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected resolved edge %s -> %s, got %v", tt.mainID, tt.saveID, analyzer.Relationships)
+			}
+		})
+	}
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.IDStyle = "bogus"
+	if err := analyzer.Analyze(); err == nil {
+		t.Error("Expected an error for an unknown ID style")
+	}
+}
+
+func TestAnalyzeImportPathIDs(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"main.go": `package main
+
+import (
+	"log"
+
+	mylog "example.com/test/log"
+)
+
+func main() {
+	mylog.Printf("x")
+	log.Printf("y")
+}
+`,
+		// A repo package named like a standard library one.
+		"log/log.go": "package log\n\nfunc Printf(format string, args ...any) {}\n",
+		// A nested module has its own module path.
+		"tools/go.mod":  "module example.org/tools\n\ngo 1.25\n",
+		"tools/lint.go": "package tools\n\nfunc Lint() {}\n",
+	})
+	analyzer.Reset()
+	analyzer.IDStyle = IDStyleImportPath
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	for _, id := range []string{"example.com/test.main", "example.com/test/log.Printf", "example.org/tools.Lint"} {
+		if !analyzer.CollectedNodeIDs[id] {
+			t.Errorf("expected node %s, got %v", id, analyzer.CollectedNodeIDs)
+		}
+	}
+	callees := map[string]models.CallRelationship{}
+	for _, rel := range analyzer.Relationships {
+		callees[rel.Callee] = rel
+	}
+	if rel := callees["example.com/test/log.Printf"]; !rel.IsResolved || rel.Confidence != models.ConfidenceExact {
+		t.Errorf("expected a resolved call to the repo's log.Printf, got %+v", rel)
+	}
+	if rel, ok := callees["log.Printf"]; !ok || rel.IsResolved {
+		t.Errorf("expected an unresolved call to the standard library's log.Printf, got %+v", callees)
+	}
+
+	// Without a module, IDs fall back to directories.
+	if err := os.Remove(filepath.Join(analyzer.RepoAbs, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(analyzer.RepoAbs, "tools", "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	analyzer.Reset()
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	for _, id := range []string{"main", "log.Printf", "tools.Lint"} {
+		if !analyzer.CollectedNodeIDs[id] {
+			t.Errorf("expected node %s without a module, got %v", id, analyzer.CollectedNodeIDs)
+		}
+	}
+}
+
+func TestAnalyzeUnicodePathID(t *testing.T) {
+	// Module import paths must be ASCII, so use the module-less fallback.
+	// U+012F ('į') has low byte '/', which a rune->byte cast misreads as a
+	// separator.
+	tmpDir := t.TempDir()
+	dir := filepath.Join(tmpDir, "数据", "dįr")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lįb.go"), []byte("package data\n\nfunc Load() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if !analyzer.CollectedNodeIDs["数据.dįr.lįb.Load"] {
+		t.Errorf("Expected node 数据.dįr.lįb.Load, got %v", analyzer.CollectedNodeIDs)
+	}
+}
+
+func TestAnalyzeInterfaceKind(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"kinds.go": `package testpkg
+
+type Reader interface {
+	Read() error
+}
+
+type File struct{}
+
+type ID int
+`,
+	})
+
+	nodes := map[string]models.Node{}
+	for _, node := range analyzer.Nodes {
+		nodes[node.ID] = node
+	}
+	if reader := nodes["kinds.Reader"]; reader.ComponentType != "class" || reader.NodeType != "interface" || !reader.IsInterface {
+		t.Errorf("Expected Reader to be an interface class, got %+v", reader)
+	}
+	if file := nodes["kinds.File"]; file.ComponentType != "class" || file.NodeType != "struct" || file.IsInterface {
+		t.Errorf("Expected File to be a struct class, got %+v", file)
+	}
+	if _, ok := nodes["kinds.ID"]; ok {
+		t.Error("Expected no node for a non-struct, non-interface type")
+	}
+}
+
+func TestAnalyzeFieldAndMethodExprCalls(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"client/client.go": `package client
+
+type Client struct{}
+
+func (c *Client) Do() {}
+
+func (c Client) Name() string { return "" }
+`,
+		"svc.go": `package main
+
+import (
+	"strings"
+
+	"example.com/test/client"
+)
+
+type Service struct {
+	client *client.Client
+}
+
+func (s *Service) Run() {
+	s.client.Do()
+	(*client.Client).Do(s.client)
+	client.Client.Name(client.Client{})
+	(*strings.Builder).Len(nil)
+}
+
+func main() {}
+`,
+	})
+
+	calls := map[int]models.CallRelationship{}
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "svc.Service.Run" {
+			calls[rel.CallLine] = rel
+		}
+	}
+	tests := []struct {
+		line     int
+		callee   string
+		resolved bool
+	}{
+		{14, "client.client.Client.Do", true},   // Field access
+		{15, "client.client.Client.Do", true},   // Pointer method expression
+		{16, "client.client.Client.Name", true}, // Value method expression
+		{17, "strings.Builder.Len", false},      // External method expression
+	}
+	for _, tt := range tests {
+		rel, ok := calls[tt.line]
+		if !ok {
+			t.Errorf("Expected a call on line %d, got %v", tt.line, calls)
+			continue
+		}
+		if rel.Callee != tt.callee || rel.IsResolved != tt.resolved {
+			t.Errorf("Line %d: expected %s (resolved=%v), got %s (resolved=%v)", tt.line, tt.callee, tt.resolved, rel.Callee, rel.IsResolved)
+		}
+	}
+}
+
+func TestAnalyzeInitializers(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"config.go": `package testpkg
+
+var defaults = load()
+
+var name = "static"
+
+func load() map[string]string { return nil }
+
+func register() {}
+
+func init() { register() }
+
+func init() { load() }
+`,
+	})
+
+	nodes := map[string]models.Node{}
+	for _, node := range analyzer.Nodes {
+		nodes[node.ID] = node
+	}
+	for _, id := range []string{"config.<init>", "config.init#1", "config.init#2"} {
+		if _, ok := nodes[id]; !ok {
+			t.Errorf("Expected node %s, got %v", id, analyzer.CollectedNodeIDs)
+		}
+	}
+	if node := nodes["config.<init>"]; node.StartLine != 3 || node.EndLine != 3 {
+		t.Errorf("Expected <init> to span the initializer on line 3, got %d-%d", node.StartLine, node.EndLine)
+	}
+
+	edges := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		if rel.IsResolved {
+			edges[rel.Caller+" -> "+rel.Callee] = true
+		}
+	}
+	for _, want := range []string{
+		"config.<init> -> config.load",
+		"config.init#1 -> config.register",
+		"config.init#2 -> config.load",
+	} {
+		if !edges[want] {
+			t.Errorf("Expected edge %s, got %v", want, edges)
+		}
+	}
+}
+
+func TestAnalyzeFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	files := map[string]string{
+		"api/api.go":      "package api\n\nimport \"example.com/test/store\"\n\nfunc Get() { store.Load() }\n",
+		"api/other.go":    "package api\n\nfunc Other() {}\n",
+		"store/store.go":  "package store\n\nfunc Load() { helper() }\n",
+		"store/helper.go": "package store\n\nfunc helper() {}\n",
+	}
+	writeFiles(t, tmpDir, files)
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.Files = []string{"api/api.go", filepath.Join(tmpDir, "store", "store.go")}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	ids := []string{}
+	for _, node := range analyzer.Nodes {
+		ids = append(ids, node.ID)
+	}
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != "api.api.Get,store.store.Load" {
+		t.Errorf("Expected only nodes from the named files, got %v", ids)
+	}
+
+	edges := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		edges[rel.Caller+" -> "+rel.Callee] = rel.IsResolved
+	}
+	if !edges["api.api.Get -> store.store.Load"] {
+		t.Errorf("Expected the call between the named files to resolve, got %v", edges)
+	}
+	if resolved, ok := edges["store.store.Load -> store.helper.helper"]; !ok || resolved {
+		t.Errorf("Expected the call out of the set to be recorded unresolved, got %v", edges)
+	}
+
+	// A named file outside every module is parsed on its own next to
+	// those loaded from one.
+	mixedDir := t.TempDir()
+	writeFiles(t, mixedDir, map[string]string{
+		"mod/go.mod": "module example.com/mod\n\ngo 1.25\n",
+		"mod/a.go":   "package a\n\nfunc InMod() {}\n",
+		"loose.go":   "package loose\n\nfunc Loose() { helper() }\n\nfunc helper() {}\n",
+	})
+	analyzer, err = NewGoAnalyzer(mixedDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.Files = []string{"mod/a.go", "loose.go"}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	ids = ids[:0]
+	for _, node := range analyzer.Nodes {
+		ids = append(ids, node.ID)
+	}
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != "loose.Loose,loose.helper,mod.a.InMod" {
+		t.Errorf("Expected nodes from both named files, got %v", ids)
+	}
+	if !slices.Contains(analyzer.Diagnostics, "loose.go is not in a module: type resolution is disabled for it") {
+		t.Errorf("Expected a diagnostic for the file outside every module, got %v", analyzer.Diagnostics)
+	}
+}
+
+func TestAnalyzeMarkStdlib(t *testing.T) {
+	// A third-party module replaced with a local directory outside the repo.
+	root := t.TempDir()
+	depDir := filepath.Join(root, "dep")
+	repoDir := filepath.Join(root, "repo")
+	for _, dir := range []string{depDir, repoDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(depDir, "go.mod"): "module example.org/dep\n\ngo 1.25\n",
+		filepath.Join(depDir, "dep.go"): "package dep\n\nfunc Do() {}\n",
+		filepath.Join(repoDir, "go.mod"): "module example.com/test\n\ngo 1.25\n\n" +
+			"require example.org/dep v0.0.0\n\nreplace example.org/dep => ../dep\n",
+		filepath.Join(repoDir, "main.go"): `package main
+
+import (
+	"fmt"
+	"io/fs"
+
+	"example.org/dep"
+)
+
+func main() {
+	fmt.Println("hi")
+	dep.Do()
+	local()
+}
+
+func local() {}
+`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	analyzer, err := NewGoAnalyzer(repoDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.MarkStdlib = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	stdlib := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		stdlib[rel.Callee] = rel.IsStdlib
+	}
+	want := map[string]bool{"fmt.Println": true, "dep.Do": false, "main.local": false}
+	for callee, isStdlib := range want {
+		got, ok := stdlib[callee]
+		if !ok {
+			t.Errorf("Expected a call to %s, got %v", callee, stdlib)
+			continue
+		}
+		if got != isStdlib {
+			t.Errorf("Expected %s to have IsStdlib=%v, got %v", callee, isStdlib, got)
+		}
+	}
+}
+
+func TestAnalyzeLocalTypes(t *testing.T) {
+	files := map[string]string{
+		"build.go": `package testpkg
+
+func Build() any {
+	type result struct {
+		ok bool
+	}
+	return &result{}
+}
+`,
+	}
+
+	analyzer := analyzeFiles(t, files)
+	for _, node := range analyzer.Nodes {
+		if node.Name == "result" {
+			t.Errorf("Expected local types to be skipped by default, got %s", node.ID)
+		}
+	}
+
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, "build.go"), []byte(files["build.go"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.ExtractLocalTypes = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	var local *models.Node
+	for i := range analyzer.Nodes {
+		if analyzer.Nodes[i].Name == "result" {
+			local = &analyzer.Nodes[i]
+		}
+	}
+	if local == nil {
+		t.Fatal("Expected a node for the local type")
+	}
+	if local.ID != "build.Build$result" || local.EnclosingFunc != "build.Build" {
+		t.Errorf("Expected ID build.Build$result enclosed by build.Build, got %s in %q", local.ID, local.EnclosingFunc)
+	}
+	if local.StartLine != 4 || local.EndLine != 6 {
+		t.Errorf("Expected the local type to span lines 4-6, got %d-%d", local.StartLine, local.EndLine)
+	}
+
+	found := false
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "build.Build" && rel.Callee == "build.Build$result" && rel.RelationshipType == "produces" {
+			found = rel.IsResolved
+		}
+	}
+	if !found {
+		t.Errorf("Expected a resolved produces edge to the local type, got %+v", analyzer.Relationships)
+	}
+}
+
+func TestAnalyzeContextCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err = analyzer.AnalyzeContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected a cancelled context to return promptly, took %s", elapsed)
+	}
+	if len(analyzer.Nodes) != 0 {
+		t.Errorf("Expected no nodes from a cancelled analysis, got %d", len(analyzer.Nodes))
+	}
+}
+
+func TestAnalyzeGenericReceiver(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"stack.go": `package testpkg
+
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) Push(v T) {
+	s.items = append(s.items, v)
+}
+
+type Pair[K comparable, V any] struct{}
+
+func (p Pair[K, V]) Key() {}
+
+func Use() {
+	var s Stack[int]
+	s.Push(1)
+	Pair[string, int]{}.Key()
+}
+`,
+	})
+
+	nodes := map[string]models.Node{}
+	for _, node := range analyzer.Nodes {
+		nodes[node.ID] = node
+	}
+	push, ok := nodes["stack.Stack.Push"]
+	if !ok {
+		t.Fatalf("Expected method ID stack.Stack.Push, got %v", analyzer.CollectedNodeIDs)
+	}
+	if push.ClassName != "Stack" || push.ReceiverType != "*Stack[T]" || push.OwnerTypeID != "stack.Stack" {
+		t.Errorf("Expected Push on Stack (*Stack[T]) owned by stack.Stack, got class %q receiver %q owner %q",
+			push.ClassName, push.ReceiverType, push.OwnerTypeID)
+	}
+	if key := nodes["stack.Pair.Key"]; key.ReceiverType != "Pair[K, V]" {
+		t.Errorf("Expected stack.Pair.Key with receiver Pair[K, V], got %+v", key)
+	}
+
+	resolved := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "stack.Use" {
+			resolved[rel.Callee] = rel.IsResolved
+		}
+	}
+	for _, callee := range []string{"stack.Stack.Push", "stack.Pair.Key"} {
+		if !resolved[callee] {
+			t.Errorf("Expected a resolved call to %s, got %v", callee, resolved)
+		}
+	}
+}
+
+func TestAnalyzeGitignore(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	files := map[string]string{
+		".gitignore":       "gen/\n*_generated.go\n",
+		"main.go":          "package main\n\nfunc main() {}\n",
+		"api_generated.go": "package main\n\nfunc Generated() {}\n",
+		"gen/gen.go":       "package gen\n\nfunc Gen() {}\n",
+		"gen/tool/go.mod":  "module example.com/tool\n\ngo 1.25\n",
+		"gen/tool/tool.go": "package tool\n\nfunc Tool() {}\n",
+		"keep/keep.go":     "package keep\n\nfunc Keep() {}\n",
+	}
+	writeFiles(t, tmpDir, files)
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.UseGitignore = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	ids := []string{}
+	for _, node := range analyzer.Nodes {
+		ids = append(ids, node.ID)
+	}
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != "keep.keep.Keep,main.main" {
+		t.Errorf("Expected gitignored files and modules to be skipped, got %v", ids)
+	}
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	analyzer, err := NewGoAnalyzer(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	err = analyzer.Analyze()
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing repo to match fs.ErrNotExist, got %v", err)
+	}
+	var readErr *ReadError
+	if !errors.As(err, &readErr) {
+		t.Errorf("Expected a *ReadError, got %T", err)
+	}
+
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, "a.go"), []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	analyzer, err = NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.Patterns = []string{"./nothing/..."}
+	if err := analyzer.Analyze(); !errors.Is(err, ErrNoPackages) {
+		t.Errorf("Expected ErrNoPackages for a pattern matching nothing, got %v", err)
+	}
+}
+
+func TestAnalyzeWithFileSetAndReset(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	path := filepath.Join(tmpDir, "a.go")
+	if err := os.WriteFile(path, []byte("package a\n\nfunc A() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	fset.AddFile("earlier.go", -1, 1000) // Positions must not start at the base
+	analyzer, err := NewGoAnalyzer(tmpDir, WithFileSet(fset))
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if analyzer.FileSet != fset {
+		t.Fatal("Expected the analyzer to use the injected FileSet")
+	}
+
+	var file *token.File
+	fset.Iterate(func(f *token.File) bool {
+		if f.Name() == path {
+			file = f
+		}
+		return true
+	})
+	if file == nil {
+		t.Fatalf("Expected %s to be added to the injected FileSet", path)
+	}
+	if file.Base() <= 1000 {
+		t.Errorf("Expected the file to follow earlier.go in the FileSet, got base %d", file.Base())
+	}
+	if len(analyzer.Nodes) != 1 {
+		t.Fatalf("Expected 1 node, got %d", len(analyzer.Nodes))
+	}
+	node := analyzer.Nodes[0]
+	if pos := fset.Position(file.Pos(node.StartByte)); pos.Filename != path || pos.Line != node.StartLine {
+		t.Errorf("Expected node offsets to map back through the injected FileSet, got %v for line %d", pos, node.StartLine)
+	}
+
+	analyzer.Reset()
+	if len(analyzer.Nodes) != 0 || len(analyzer.Relationships) != 0 || len(analyzer.CollectedNodeIDs) != 0 {
+		t.Error("Expected Reset to clear the results")
+	}
+	if analyzer.FileSet == fset || analyzer.FileSet == nil {
+		t.Error("Expected Reset to replace the FileSet")
+	}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze after Reset failed: %v", err)
+	}
+	if len(analyzer.Nodes) != 1 {
+		t.Errorf("Expected a clean rerun after Reset, got %d nodes", len(analyzer.Nodes))
+	}
+}
+
+func TestAnalyzeDisplayNames(t *testing.T) {
+	content := `package testpkg
+
+import "io"
+
+type T struct{}
+
+func Open(a int, b string) (err error) { return nil }
+
+func (t *T) Copy(w io.Writer, opts ...string) (int, error) { return 0, nil }
+
+func Name() string { return "" }
+`
+	want := map[string]string{
+		"sig.Open":   "func Open(a int, b string) (err error)",
+		"sig.T.Copy": "method (t *T) Copy(w io.Writer, opts ...string) (int, error)",
+		"sig.Name":   "func Name() string",
+	}
+
+	typed := analyzeFiles(t, map[string]string{"sig.go": content})
+
+	untypedDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(untypedDir, "sig.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	untyped, err := NewGoAnalyzer(untypedDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	if err := untyped.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	for name, analyzer := range map[string]*GoAnalyzer{"typed": typed, "untyped": untyped} {
+		got := map[string]models.Node{}
+		for _, node := range analyzer.Nodes {
+			got[node.ID] = node
+		}
+		for id, display := range want {
+			if got[id].DisplayName != display {
+				t.Errorf("%s: expected %s to display as %q, got %q", name, id, display, got[id].DisplayName)
+			}
+		}
+		if got["sig.T.Copy"].ShortName != "method T.Copy" {
+			t.Errorf("%s: expected short name %q, got %q", name, "method T.Copy", got["sig.T.Copy"].ShortName)
+		}
+	}
+}
+
+func TestAnalyzeParenthesizedCalls(t *testing.T) {
+	content := `package main
+
+import "strings"
+
+type T struct{}
+
+func (t T) Get() {}
+
+func helper(args ...int) {}
+
+func main() {
+	(helper)(1, 2)
+	(helper)([]int{1}...)
+	(T{}.Get)()
+	(strings.TrimSpace)(" ")
+}
+`
+	typed := analyzeFiles(t, map[string]string{"paren.go": content})
+
+	untypedDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(untypedDir, "paren.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	untyped, err := NewGoAnalyzer(untypedDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	if err := untyped.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	for name, analyzer := range map[string]*GoAnalyzer{"typed": typed, "untyped": untyped} {
+		calls := map[int]string{}
+		for _, rel := range analyzer.Relationships {
+			if rel.Caller == "paren.main" {
+				calls[rel.CallLine] = rel.Callee
+			}
+		}
+		for line, callee := range map[int]string{12: "paren.helper", 13: "paren.helper", 15: "strings.TrimSpace"} {
+			if calls[line] != callee {
+				t.Errorf("%s: expected a call to %s on line %d, got %v", name, callee, line, calls)
+			}
+		}
+		if name == "typed" && calls[14] != "paren.T.Get" {
+			t.Errorf("typed: expected a call to paren.T.Get on line 14, got %v", calls)
+		}
+	}
+}
+
+func TestAnalyzePromotedMethodCalls(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"a/base.go": `package a
+
+type Base struct{}
+
+func (b *Base) Close() {}
+
+type Inner struct{ *Base }
+`,
+		"b/wrapper.go": `package b
+
+import "example.com/test/a"
+
+type Wrapper struct {
+	a.Base
+}
+
+type Deep struct {
+	a.Inner
+}
+
+func (w *Wrapper) Close() {}
+
+func Use(w *Wrapper, d Deep) {
+	w.Base.Close()
+	d.Close()
+	(*Deep).Close(&d)
+}
+`,
+	})
+
+	calls := map[int]models.CallRelationship{}
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "b.wrapper.Use" {
+			calls[rel.CallLine] = rel
+		}
+	}
+	for _, line := range []int{16, 17, 18} {
+		rel, ok := calls[line]
+		if !ok {
+			t.Errorf("Expected a call on line %d, got %v", line, calls)
+			continue
+		}
+		if rel.Callee != "a.base.Base.Close" || !rel.IsResolved {
+			t.Errorf("Line %d: expected a resolved call to a.base.Base.Close, got %s (resolved=%v)", line, rel.Callee, rel.IsResolved)
+		}
+	}
+}
+
+func TestAnalyzeSlashPaths(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{filepath.Join("pkg", "sub", "file.go"), "pkg/sub/file.go"},
+		{`pkg\sub\file.go`, "pkg/sub/file.go"},
+		{"file.go", "file.go"},
+	} {
+		if got := slashPath(tt.in); got != tt.want {
+			t.Errorf("slashPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	analyzer := analyzeFiles(t, map[string]string{"pkg/sub/file.go": "package sub\n\nfunc F() {}\n"})
+	node := analyzer.Nodes[0]
+	if node.RelativePath != "pkg/sub/file.go" || strings.Contains(node.FilePath, `\`) {
+		t.Errorf("Expected forward-slash paths, got %q and %q", node.RelativePath, node.FilePath)
+	}
+	// Both separators give the same ID.
+	for _, rel := range []string{filepath.Join("pkg", "sub", "file.go"), `pkg\sub\file.go`} {
+		if id := analyzer.getComponentIDForFile(filepath.Join(analyzer.RepoAbs, rel), "F", ""); id != "pkg.sub.file.F" {
+			t.Errorf("Expected ID pkg.sub.file.F for %q, got %s", rel, id)
+		}
+	}
+}
+
+func TestAnalyzeSummary(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"shape/shape.go": `package shape
+
+import "fmt"
+
+type Shape interface{ Area() float64 }
+
+type Square struct{ Side float64 }
+
+func (s Square) Area() float64 { return s.Side * s.Side }
+
+func Describe(s Shape) { fmt.Println(s.Area()) }
+`,
+		"main.go": `package main
+
+import "example.com/test/shape"
+
+func main() { shape.Describe(shape.Square{Side: 2}) }
+`,
+	})
+
+	want := models.Summary{
+		TotalNodes:         len(analyzer.Nodes),
+		Functions:          2,
+		Methods:            1,
+		Structs:            1,
+		Interfaces:         1,
+		TotalRelationships: len(analyzer.Relationships),
+		PackagesAnalyzed:   2,
+	}
+	for _, rel := range analyzer.Relationships {
+		if rel.IsResolved {
+			want.ResolvedRelationships++
+		} else {
+			want.UnresolvedRelationships++
+		}
+	}
+	if want.TotalNodes != 5 || want.ResolvedRelationships == 0 || want.UnresolvedRelationships == 0 {
+		t.Fatalf("Unexpected fixture results: %+v", want)
+	}
+	if analyzer.Summary != want {
+		t.Errorf("Expected summary %+v, got %+v", want, analyzer.Summary)
+	}
+}
+
+func TestAnalyzeElementMethodCalls(t *testing.T) {
+	content := `package main
+
+type Item struct{}
+
+func (i *Item) Save() {}
+
+func main() {
+	items := []*Item{{}}
+	byName := map[string]Item{}
+	var fixed [2]Item
+	items[0].Save()
+	v := byName["a"]
+	v.Save()
+	fixed[1].Save()
+}
+`
+	typed := analyzeFiles(t, map[string]string{"items.go": content})
+
+	untypedDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(untypedDir, "items.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	untyped, err := NewGoAnalyzer(untypedDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	if err := untyped.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	tests := []struct {
+		analyzer *GoAnalyzer
+		callee   string
+		resolved bool
+	}{
+		{typed, "items.Item.Save", true},
+		{untyped, "Save", false},
+	}
+	for _, tt := range tests {
+		calls := map[int]models.CallRelationship{}
+		for _, rel := range tt.analyzer.Relationships {
+			calls[rel.CallLine] = rel
+		}
+		for _, line := range []int{11, 14} {
+			rel, ok := calls[line]
+			if !ok || rel.Callee != tt.callee || rel.IsResolved != tt.resolved {
+				t.Errorf("Line %d: expected %s (resolved=%v), got %+v", line, tt.callee, tt.resolved, rel)
+			}
+		}
+	}
+}
+
+func TestAnalyzeInternalAsPrivate(t *testing.T) {
+	files := map[string]string{
+		"internal/store/store.go": `package store
+
+func Open() {}
+
+func Close() { Open() }
+`,
+		"api/api.go": `package api
+
+import "example.com/test/internal/store"
+
+func Start() { store.Open() }
+`,
+	}
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	writeFiles(t, tmpDir, files)
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.InternalAsPrivate = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	for _, node := range analyzer.Nodes {
+		internal := strings.HasPrefix(node.ID, "internal.")
+		if (node.Visibility == "internal") != internal {
+			t.Errorf("Node %s: unexpected visibility %q", node.ID, node.Visibility)
+		}
+	}
+	for _, rel := range analyzer.Relationships {
+		if want := rel.Caller == "api.api.Start"; rel.IntoInternal != want {
+			t.Errorf("Relationship %s -> %s: expected into_internal=%v", rel.Caller, rel.Callee, want)
+		}
+	}
+
+	analyzer.Reset()
+	analyzer.ExportedOnly = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(analyzer.Nodes) != 1 || analyzer.Nodes[0].ID != "api.api.Start" {
+		t.Errorf("Expected only api.api.Start with ExportedOnly, got %v", analyzer.Nodes)
+	}
+	if len(analyzer.Relationships) != 1 || analyzer.Relationships[0].IsResolved || !analyzer.Relationships[0].IntoInternal {
+		t.Errorf("Expected the call into internal/ to be kept unresolved and flagged, got %+v", analyzer.Relationships)
+	}
+}
+
+func TestAnalyzeFuncFieldCalls(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"hooks/hooks.go": `package hooks
+
+type Callback func(req string)
+`,
+		"server.go": `package main
+
+import "example.com/test/hooks"
+
+type Server struct {
+	handler  func(req string) error
+	fallback hooks.Callback
+}
+
+func (s *Server) Serve(req string) {
+	s.handler(req)
+	s.fallback(req)
+}
+
+func main() {}
+`,
+	})
+
+	var callees []string
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "server.Server.Serve" && rel.RelationshipType == "calls" {
+			callees = append(callees, rel.Callee)
+			if rel.IsResolved {
+				t.Errorf("Expected calls through fields to be unresolved, got %+v", rel)
+			}
+		}
+	}
+	if len(callees) != 1 || callees[0] != "hooks.Callback" {
+		t.Errorf("Expected only an edge to hooks.Callback, got %v", callees)
+	}
+}
+
+func TestAnalyzeLineComments(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	content := `package testpkg
+
+// Token is documented.
+type Token struct{} // opaque handle
+
+type (
+	A struct{} // first
+	B interface {
+		M() // method comment
+	}
+)
+
+func Run() {} /* runs */
+
+func Stop() {
+} // stops
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "token.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.LineComments = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	want := map[string]string{
+		"token.Token": "opaque handle",
+		"token.A":     "first",
+		"token.B":     "",
+		"token.Run":   "runs",
+		"token.Stop":  "stops",
+	}
+	for _, node := range analyzer.Nodes {
+		if node.LineComment != want[node.ID] {
+			t.Errorf("%s: expected line comment %q, got %q", node.ID, want[node.ID], node.LineComment)
+		}
+		if node.ID == "token.Token" && node.Docstring != "Token is documented.\n" {
+			t.Errorf("Expected the doc comment to stay in Docstring, got %q", node.Docstring)
+		}
+	}
+}
+
+func TestAnalyzeCrossModuleCalls(t *testing.T) {
+	// Two modules in one repo, the first using the second through a
+	// replace directive. Each is loaded separately.
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"svc/go.mod": "module example.com/svc\n\ngo 1.25\n\n" +
+			"require example.com/lib v0.0.0\n\nreplace example.com/lib => ../lib\n",
+		"svc/main.go": `package main
+
+import "example.com/lib/text"
+
+func main() {
+	text.Greet()
+	var p text.Printer
+	p.Print()
+}
+`,
+		"lib/go.mod": "module example.com/lib\n\ngo 1.25\n",
+		"lib/text/text.go": `package text
+
+type Printer struct{}
+
+func (p Printer) Print() {}
+
+func Greet() {}
+`,
+	}
+	writeFiles(t, repoDir, files)
+
+	analyzer, err := NewGoAnalyzer(repoDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	edges := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "svc.main.main" {
+			edges[rel.Callee] = rel.IsResolved
+		}
+	}
+	for _, callee := range []string{"lib.text.text.Greet", "lib.text.text.Printer.Print"} {
+		if resolved, ok := edges[callee]; !ok || !resolved {
+			t.Errorf("Expected a resolved call to %s, got %v", callee, edges)
+		}
+	}
+}
+
+func TestAnalyzeConcurrentModuleLoads(t *testing.T) {
+	files := map[string]string{"main.go": "package main\n\nfunc main() {}\n"}
+	for _, name := range []string{"alpha", "beta", "gamma", "delta"} {
+		files[name+"/go.mod"] = "module example.com/" + name + "\n\ngo 1.25\n"
+		files[name+"/"+name+".go"] = "package " + name + `
+
+type Store struct{}
+
+func (s *Store) Get() string { return helper() }
+
+func helper() string { return "" }
+`
+	}
+	analyzer := analyzeFiles(t, files)
+
+	analyzer.Reset()
+	analyzer.Jobs = 1
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	sequential := analyzer.Result()
+	if got := sequential.Summary.PackagesAnalyzed; got != 5 {
+		t.Fatalf("expected 5 packages, got %d", got)
+	}
+
+	for range 3 {
+		analyzer.Reset()
+		analyzer.Jobs = 4
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		concurrent := analyzer.Result()
+		if !reflect.DeepEqual(concurrent.Nodes, sequential.Nodes) || !reflect.DeepEqual(concurrent.CallRelationships, sequential.CallRelationships) {
+			t.Fatalf("expected the same results with Jobs = 4 as with Jobs = 1")
+		}
+	}
+}
+
+func TestAnalyzePackagePath(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"main.go":              "package main\n\nfunc main() {}\n",
+		"internal/codec/io.go": "package codec\n\ntype Codec struct{}\n\nfunc (c Codec) Encode() {}\n",
+	})
+
+	want := map[string]string{
+		"main.main":                      "example.com/test",
+		"internal.codec.io.Codec":        "example.com/test/internal/codec",
+		"internal.codec.io.Codec.Encode": "example.com/test/internal/codec",
+	}
+	for _, node := range analyzer.Nodes {
+		if node.PackagePath != want[node.ID] {
+			t.Errorf("%s: expected package path %q, got %q", node.ID, want[node.ID], node.PackagePath)
+		}
+	}
+}
+
+func TestAnalyzePackageNameAndDocs(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"cmd/tool.go":    "package main\n\nfunc main() {}\n",
+		"util/a.go":      "package util\n\nfunc A() {}\n",
+		"util/doc.go":    "// Package util has helpers.\npackage util\n",
+		"util/z.go":      "// Package util is documented twice.\npackage util\n",
+		"plain/plain.go": "package plain\n\nfunc P() {}\n",
+	})
+	for _, node := range analyzer.Nodes {
+		if want := map[string]string{"cmd.tool.main": "main", "util.a.A": "util"}[node.ID]; want != "" && node.PackageName != want {
+			t.Errorf("%s: expected package name %q, got %q", node.ID, want, node.PackageName)
+		}
+	}
+	if analyzer.PackageDocs != nil {
+		t.Errorf("expected no package docs by default, got %v", analyzer.PackageDocs)
+	}
+
+	analyzer.Reset()
+	analyzer.CollectPackageDocs = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	want := map[string]string{"example.com/test/util": "Package util has helpers.\n"}
+	if !maps.Equal(analyzer.PackageDocs, want) {
+		t.Errorf("expected package docs %v, got %v", want, analyzer.PackageDocs)
+	}
+}
+
+func TestAnalyzeSyntaxErrors(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"good.go": "package testpkg\n\nfunc Good() {}\n",
+		"bad.go":  "package testpkg\n\nfunc Bad( {\n\ntype Broken struct {\n",
+	})
+
+	found := false
+	for _, node := range analyzer.Nodes {
+		found = found || node.ID == "good.Good"
+	}
+	if !found {
+		t.Errorf("Expected good.Good despite the broken file, got %v", analyzer.Nodes)
+	}
+	if len(analyzer.Diagnostics) == 0 || !strings.Contains(analyzer.Diagnostics[0], "bad.go:3:") {
+		t.Errorf("Expected a diagnostic naming bad.go, got %v", analyzer.Diagnostics)
+	}
+
+	// A panic in a pass is recorded and the run goes on.
+	if analyzer.collectFile(filepath.Join(analyzer.RepoAbs, "bad.go"), func() { panic("nil node") }) {
+		t.Error("Expected collectFile to report the panic")
+	}
+	last := analyzer.Diagnostics[len(analyzer.Diagnostics)-1]
+	if last != "skipped the rest of bad.go after an internal error: nil node" {
+		t.Errorf("Unexpected diagnostic %q", last)
+	}
+}
+
+func TestAnalyzeBaseClasses(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"conn.go": `package testpkg
+
+import (
+	"io"
+	"sync"
+)
+
+type List[T any] struct{}
+
+type Conn struct {
+	*sync.Mutex
+	name string
+	io.Reader
+	List[int]
+}
+
+type Plain struct{ name string }
+`})
+
+	want := map[string][]string{
+		"conn.Conn":  {"sync.Mutex", "io.Reader", "List[int]"},
+		"conn.Plain": nil,
+		"conn.List":  nil,
+	}
+	for _, node := range analyzer.Nodes {
+		if got := node.BaseClasses; strings.Join(got, ",") != strings.Join(want[node.ID], ",") {
+			t.Errorf("%s: expected base classes %v, got %v", node.ID, want[node.ID], got)
+		}
+	}
+}
+
+func TestAnalyzeFanInFanOut(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"hub.go": `package testpkg
+
+func Hub(n int) {
+	if n > 0 {
+		Hub(n - 1)
+	}
+	Load()
+	Load()
+	Save()
+}
+
+func Load() {}
+
+func Save() {}
+
+func A() { Hub(1) }
+
+func B() { Hub(2); Hub(3) }
+
+func C() { Hub(4) }
+`})
+
+	nodes := map[string]models.Node{}
+	for _, node := range analyzer.Nodes {
+		nodes[node.ID] = node
+	}
+	if hub := nodes["hub.Hub"]; hub.FanIn != 3 || hub.FanOut != 2 {
+		t.Errorf("Expected hub.Hub fan_in=3 fan_out=2, got fan_in=%d fan_out=%d", hub.FanIn, hub.FanOut)
+	}
+	if load := nodes["hub.Load"]; load.FanIn != 1 || load.FanOut != 0 {
+		t.Errorf("Expected hub.Load fan_in=1 fan_out=0, got fan_in=%d fan_out=%d", load.FanIn, load.FanOut)
+	}
+}
+
+func TestAnalyzeTypeParamMethodCalls(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"gen.go": `package testpkg
+
+import "fmt"
+
+type Stringer interface{ String() string }
+
+type Named interface {
+	Stringer
+	Name() string
+}
+
+func Show[T Stringer](t T) string { return t.String() }
+
+func ShowAll[T Named](t T) { t.String() }
+
+func Inline[T interface{ Len() int }](t T) int { return t.Len() }
+
+func Std[T fmt.Stringer](t T) { t.String() }
+`})
+
+	want := map[string]string{
+		"gen.Show":    "gen.Stringer.String",
+		"gen.ShowAll": "gen.Stringer.String", // Declared by the embedded constraint
+		"gen.Inline":  "T.Len",
+		"gen.Std":     "fmt.Stringer.String",
+	}
+	got := map[string]string{}
+	for _, rel := range analyzer.Relationships {
+		got[rel.Caller] = rel.Callee
+	}
+	for caller, callee := range want {
+		if got[caller] != callee {
+			t.Errorf("%s: expected a call to %s, got %q", caller, callee, got[caller])
+		}
+	}
+}
+
+func TestAnalyzeExcludeGenerated(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"main.go": "package main\n\nfunc main() { Gen() }\n",
+		"gen.go":  "// Code generated by stringer; DO NOT EDIT.\n\npackage main\n\nfunc Gen() {}\n",
+		"late.go": "package main\n\n// Code generated by hand; DO NOT EDIT.\n\nfunc Late() {}\n", // After the package clause
+	})
+
+	for _, exclude := range []bool{false, true} {
+		analyzer.Reset()
+		analyzer.ExcludeGenerated = exclude
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze failed (exclude=%v): %v", exclude, err)
+		}
+		if analyzer.CollectedNodeIDs["gen.Gen"] == exclude {
+			t.Errorf("exclude=%v: unexpected presence of gen.Gen: %v", exclude, analyzer.CollectedNodeIDs)
+		}
+		if !analyzer.CollectedNodeIDs["late.Late"] {
+			t.Errorf("exclude=%v: expected late.Late, whose marker follows the package clause", exclude)
+		}
+		// Calls into generated code still resolve to its IDs.
+		found := false
+		for _, rel := range analyzer.Relationships {
+			if rel.Caller == "main.main" && rel.Callee == "gen.Gen" {
+				found = rel.IsResolved != exclude
+			}
+		}
+		if !found {
+			t.Errorf("exclude=%v: expected a call to gen.Gen, got %v", exclude, analyzer.Relationships)
+		}
+	}
+}
+
+func TestAnalyzerResult(t *testing.T) {
+	var analyzer Analyzer = analyzeFiles(t, map[string]string{
+		"main.go": "package main\n\nfunc main() { helper() }\n\nfunc helper() {}\n",
+	})
+
+	result := analyzer.Result()
+	if result.SchemaVersion != models.SchemaVersion {
+		t.Errorf("expected schema version %s, got %s", models.SchemaVersion, result.SchemaVersion)
+	}
+	if len(result.Nodes) != 2 || len(result.CallRelationships) != 1 {
+		t.Fatalf("expected 2 nodes and 1 relationship, got %v and %v", result.Nodes, result.CallRelationships)
+	}
+	if rel := result.CallRelationships[0]; rel.Caller != "main.main" || rel.Callee != "main.helper" || !rel.IsResolved {
+		t.Errorf("unexpected relationship %+v", rel)
+	}
+	if result.Summary == nil || result.Summary.Functions != 2 {
+		t.Errorf("expected a summary counting 2 functions, got %+v", result.Summary)
+	}
+}
+
+func TestAnalyzeTwiceRequiresReset(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+
+	if err := analyzer.Analyze(); !errors.Is(err, ErrAlreadyAnalyzed) {
+		t.Fatalf("expected ErrAlreadyAnalyzed, got %v", err)
+	}
+	if len(analyzer.Nodes) != 1 {
+		t.Errorf("expected the first run's single node to be kept, got %v", analyzer.Nodes)
+	}
+
+	analyzer.Reset()
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze after Reset failed: %v", err)
+	}
+	if len(analyzer.Nodes) != 1 {
+		t.Errorf("expected 1 node after Reset, got %v", analyzer.Nodes)
+	}
+}
+
+func TestAnalyzeAfterInvalidOptions(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+
+	analyzer.Reset()
+	analyzer.IDStyle = "bogus"
+	if err := analyzer.Analyze(); err == nil || errors.Is(err, ErrAlreadyAnalyzed) {
+		t.Fatalf("expected an unknown ID style error, got %v", err)
+	}
+	analyzer.IDStyle = IDStyleDottedPath
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("expected Analyze to run once the option is fixed, got %v", err)
+	}
+	if len(analyzer.Nodes) != 1 {
+		t.Errorf("expected 1 node, got %v", analyzer.Nodes)
+	}
+}
+
+// BenchmarkAnalyze measures Analyze alone, without JSON encoding, on a
+// generated module of benchFiles files that call into each other.
+func BenchmarkAnalyze(b *testing.B) {
+	const benchFiles = 50
+	dir := b.TempDir()
+	writeGoMod(b, dir)
+	for i := range benchFiles {
+		src := fmt.Sprintf("package main\n\ntype T%[1]d struct{ n int }\n\nfunc (t *T%[1]d) Inc() { t.n++ }\n\nfunc F%[1]d() {\n\tt := &T%[1]d{}\n\tt.Inc()\n", i)
+		if i > 0 {
+			src += fmt.Sprintf("\tF%d()\n", i-1)
+		}
+		src += "}\n"
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.go", i)), []byte(src), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	analyzer, err := NewGoAnalyzer(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for b.Loop() {
+		analyzer.Reset()
+		if err := analyzer.Analyze(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if got := len(analyzer.Result().Nodes); got != 3*benchFiles {
+		b.Fatalf("expected %d nodes, got %d", 3*benchFiles, got)
+	}
+}
+
+func TestAnalyzeMaxSourceBytes(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"main.go": "package main\n\nfunc Long() {\n\tprintln(\"héllo, wörld\")\n\tprintln(\"more\")\n}\n\nfunc Short() {}\n",
+	})
+	full := map[string]models.Node{}
+	for _, node := range analyzer.Nodes {
+		full[node.Name] = node
+	}
+
+	analyzer.Reset()
+	analyzer.MaxSourceBytes = 26 // Inside the "é" of Long
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	for _, node := range analyzer.Nodes {
+		want := full[node.Name]
+		switch node.Name {
+		case "Long":
+			if !node.SourceTruncated || node.SourceCode != "func Long() {\n\tprintln(\"h"+sourceTruncatedMarker {
+				t.Errorf("expected Long to be truncated before the é, got %v %q", node.SourceTruncated, node.SourceCode)
+			}
+		case "Short":
+			if node.SourceTruncated || node.SourceCode != want.SourceCode {
+				t.Errorf("expected Short to be kept whole, got %v %q", node.SourceTruncated, node.SourceCode)
+			}
+		}
+		if node.EndLine != want.EndLine || node.EndByte != want.EndByte || node.ContentHash != want.ContentHash {
+			t.Errorf("%s: expected the span and hash of the full source, got %+v", node.Name, node)
+		}
+	}
+}
+
+func TestAnalyzeCallsInSelectAndSwitch(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"main.go": `package main
+
+func compute() int      { return 1 }
+func source() chan int  { return nil }
+func handle(int)        {}
+func kind() string      { return "" }
+func isA(string) bool   { return true }
+func onA()              {}
+func fallback()         {}
+
+func Run(out chan int) {
+	select {
+	case out <- compute():
+		handle(0)
+	case v := <-source():
+		handle(v)
+	default:
+		fallback()
+	}
+	switch k := kind(); {
+	case isA(k):
+		onA()
+	default:
+		fallback()
+	}
+}
+`})
+
+	want := map[string][]int{
+		"main.compute":  {13},
+		"main.source":   {15},
+		"main.handle":   {14, 16},
+		"main.fallback": {18, 24},
+		"main.kind":     {20},
+		"main.isA":      {21},
+		"main.onA":      {22},
+	}
+	got := map[string][]int{}
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "main.Run" && rel.RelationshipType == "calls" {
+			got[rel.Callee] = append(got[rel.Callee], rel.CallLine)
+		}
+	}
+	for callee, lines := range want {
+		if !slices.Equal(got[callee], lines) {
+			t.Errorf("%s: expected calls on lines %v, got %v", callee, lines, got[callee])
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected calls to %d functions, got %v", len(want), got)
+	}
+}
+
+func TestTypeToString(t *testing.T) {
+	tests := map[string]string{
+		"*pkg.T":                           "*pkg.T",
+		"List[T]":                          "List[T]",
+		"Pair[K, V]":                       "Pair[K, V]",
+		"[]byte":                           "[]byte",
+		"[4]int":                           "[4]int",
+		"[N + 1]int":                       "[N + 1]int",
+		"[...]string":                      "[...]string",
+		"map[string][]*Node":               "map[string][]*Node",
+		"chan int":                         "chan int",
+		"chan<- error":                     "chan<- error",
+		"<-chan struct{}":                  "<-chan struct{}",
+		"func()":                           "func()",
+		"func(a, b int) error":             "func(int, int) error",
+		"func(string) (int, error)":        "func(string) (int, error)",
+		"func(n int) (err error)":          "func(int) error",
+		"func(format string, args ...any)": "func(string, ...any)",
+		"struct{ A int }":                  "struct{...}",
+		"interface{}":                      "interface{}",
+		"interface{ Close() error }":       "interface{...}",
+		"map[string]struct{ A int }":       "map[string]struct{...}",
+	}
+	for src, want := range tests {
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			t.Fatalf("ParseExpr(%q): %v", src, err)
+		}
+		if got := typeToString(expr); got != want {
+			t.Errorf("typeToString(%q) = %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestAnalyzeExternalCalleeNamesMatchWithoutTypes(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"main.go": `package main
+
+import (
+	str "strings"
+	"math/rand/v2"
+)
+
+func F() int {
+	str.ToUpper("x")
+	return rand.IntN(3)
+}
+`})
+	callees := func() []string {
+		var names []string
+		for _, rel := range analyzer.Relationships {
+			if rel.Caller == "main.F" {
+				names = append(names, rel.Callee)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+	typed := callees()
+	if want := []string{"rand.IntN", "strings.ToUpper"}; !slices.Equal(typed, want) {
+		t.Fatalf("expected callees %v with types, got %v", want, typed)
+	}
+
+	if err := os.Remove(filepath.Join(analyzer.RepoAbs, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	analyzer.Reset()
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if syntactic := callees(); !slices.Equal(syntactic, typed) {
+		t.Errorf("expected the same callees without types, got %v and %v", syntactic, typed)
+	}
+}
+
+func TestAssumedPackageName(t *testing.T) {
+	tests := map[string]string{
+		"fmt":                         "fmt",
+		"math/rand/v2":                "rand",
+		"gopkg.in/yaml.v3":            "yaml",
+		"github.com/mattn/go-sqlite3": "sqlite3",
+		"example.com/vendor":          "vendor",
+	}
+	for importPath, want := range tests {
+		if got := assumedPackageName(importPath); got != want {
+			t.Errorf("assumedPackageName(%q) = %q, want %q", importPath, got, want)
+		}
+	}
+}
+
+func TestAnalyzeCallConfidence(t *testing.T) {
+	files := map[string]string{"main.go": `package main
+
+import "strings"
+
+func helper() {}
+
+func main() {
+	helper()
+	strings.ToUpper("x")
+}
+`}
+	confidence := func(analyzer *GoAnalyzer) map[string]string {
+		got := map[string]string{}
+		for _, rel := range analyzer.Relationships {
+			got[rel.Callee] = rel.Confidence
+		}
+		return got
+	}
+
+	typed := confidence(analyzeFiles(t, files))
+	if typed["main.helper"] != models.ConfidenceExact {
+		t.Errorf("expected an exact in-repo call, got %q", typed["main.helper"])
+	}
+	if typed["strings.ToUpper"] != models.ConfidencePackageQualified {
+		t.Errorf("expected a package-qualified external call, got %q", typed["strings.ToUpper"])
+	}
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte(files["main.go"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	syntactic := confidence(analyzer)
+	if syntactic["main.helper"] != models.ConfidenceHeuristic {
+		t.Errorf("expected a heuristic same-package guess without types, got %q", syntactic["main.helper"])
+	}
+}
+
+func TestAnalyzeFieldChainMethodCalls(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"app.go": `package testpkg
+
+type Engine struct{}
+
+func (e *Engine) Start() {}
+
+type Car struct{ engine *Engine }
+
+type Garage struct{ car Car }
+
+type App struct{ garage *Garage }
+
+func Run(a App) {
+	a.garage.car.engine.Start()
+}
+`})
+	callees := func() []string {
+		var names []string
+		for _, rel := range analyzer.Relationships {
+			if rel.Caller == "app.Run" && rel.RelationshipType == "calls" {
+				names = append(names, rel.Callee)
+			}
+		}
+		return names
+	}
+	if got := callees(); !slices.Equal(got, []string{"app.Engine.Start"}) {
+		t.Errorf("expected a call to app.Engine.Start with types, got %v", got)
+	}
+
+	if err := os.Remove(filepath.Join(analyzer.RepoAbs, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	analyzer.Reset()
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if got := callees(); !slices.Equal(got, []string{"Start"}) {
+		t.Errorf("expected the method name without types, got %v", got)
+	}
+}
+
+func TestAnalyzeUnnamedReceivers(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"cache.go": `package testpkg
+
+type Cache struct{}
+
+func (*Cache) Reset() {
+	var c Cache
+	c.Flush()
+	purge()
+}
+
+func (_ Cache) Flush() {}
+
+func purge() {}
+`})
+	check := func(mode string) {
+		t.Helper()
+		found := false
+		for _, node := range analyzer.Nodes {
+			if node.ID == "cache.Cache.Reset" {
+				found = node.ComponentType == "method" && node.ClassName == "Cache" && node.ReceiverType == "*Cache"
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected a method node cache.Cache.Reset, got %+v", mode, analyzer.Nodes)
+		}
+		edges := map[string]bool{}
+		for _, rel := range analyzer.Relationships {
+			if rel.Caller == "cache.Cache.Reset" {
+				edges[rel.Callee] = true
+			}
+		}
+		for _, callee := range []string{"cache.purge", "c.Flush"} {
+			if mode == "types" && callee == "c.Flush" {
+				callee = "cache.Cache.Flush"
+			}
+			if !edges[callee] {
+				t.Errorf("%s: expected an edge to %s, got %v", mode, callee, edges)
+			}
+		}
+	}
+	check("types")
+
+	if err := os.Remove(filepath.Join(analyzer.RepoAbs, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	analyzer.Reset()
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	check("syntax")
+
+	file, _ := parser.ParseFile(token.NewFileSet(), "bad.go", "package p\n\nfunc () M() {}\n", 0)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			if typ, name := funcReceiver(fn); typ != "" || name != "" {
+				t.Errorf("expected no receiver for an empty receiver list, got %q %q", typ, name)
+			}
+		}
+	}
+}
+
+func TestAnalyzeStructFields(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"server.go": `package testpkg
+
+import "io"
+
+type Server struct {
+	*io.PipeReader
+
+	// Addr is the address to listen on,
+	// e.g. ":8080".
+	Addr string // host:port
+
+	// Bounds of the port range.
+	min, max int
+
+	handlers map[string]func() error
+}
+`})
+	want := []models.FieldInfo{
+		{Name: "PipeReader", Type: "*io.PipeReader", Embedded: true},
+		{Name: "Addr", Type: "string", Doc: "Addr is the address to listen on,\ne.g. \":8080\".\n", Comment: "host:port"},
+		{Name: "min", Type: "int", Doc: "Bounds of the port range.\n"},
+		{Name: "max", Type: "int", Doc: "Bounds of the port range.\n"},
+		{Name: "handlers", Type: "map[string]func() error"},
+	}
+	for _, node := range analyzer.Nodes {
+		if node.ID == "server.Server" {
+			if !slices.Equal(node.Fields, want) {
+				t.Errorf("expected fields %+v, got %+v", want, node.Fields)
+			}
+			return
+		}
+	}
+	t.Fatal("server.Server not found")
+}
+
+func TestAnalyzeMapIndexMethodCalls(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"registry.go": `package testpkg
+
+type Handler struct{}
+
+func (h *Handler) Handle() {}
+
+func Handle() {}
+
+var registry = map[string]*Handler{}
+
+func Dispatch(name string) {
+	registry[name].Handle()
+}
+`})
+	callees := func() map[string]models.CallRelationship {
+		found := map[string]models.CallRelationship{}
+		for _, rel := range analyzer.Relationships {
+			if rel.Caller == "registry.Dispatch" && rel.RelationshipType == "calls" {
+				found[rel.Callee] = rel
+			}
+		}
+		return found
+	}
+	if got := callees(); len(got) != 1 || !got["registry.Handler.Handle"].IsResolved {
+		t.Errorf("expected a resolved call to registry.Handler.Handle with types, got %v", got)
+	}
+
+	if err := os.Remove(filepath.Join(analyzer.RepoAbs, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	analyzer.Reset()
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	// Without types only the method name is known; it must not resolve to
+	// the package-level function of the same name.
+	got := callees()
+	if rel, ok := got["Handle"]; len(got) != 1 || !ok || rel.IsResolved || rel.Confidence != models.ConfidenceHeuristic {
+		t.Errorf("expected only an unresolved heuristic call to Handle without types, got %v", got)
+	}
+}
+
+func TestAnalyzeMethodIDsMatchOwningType(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"shape.go": `package testpkg
+
+type Shape struct{}
+
+type Alias = Shape
+
+func (s *(Shape)) Grow() { s.Shrink() }
+
+func (s (Shape)) Shrink() {}
+
+func (a Alias) Area() int { return 0 }
+
+func (a *Alias) Scale() {}
+`})
+	names := map[string]string{}
+	for _, node := range analyzer.Nodes {
+		names[node.ID] = node.Name
+	}
+	methods := 0
+	for _, node := range analyzer.Nodes {
+		if node.ComponentType != "method" {
+			continue
+		}
+		methods++
+		owner, ok := names[node.OwnerTypeID]
+		if !ok || node.ClassName != owner || node.ID != node.OwnerTypeID+"."+node.Name {
+			t.Errorf("%s: expected the ID and class name to use the owning type %q (%s), got class %q", node.ID, owner, node.OwnerTypeID, node.ClassName)
+		}
+	}
+	if methods != 4 {
+		t.Errorf("expected 4 method nodes, got %d", methods)
+	}
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "shape.Shape.Grow" && rel.RelationshipType == "calls" && (rel.Callee != "shape.Shape.Shrink" || !rel.IsResolved) {
+			t.Errorf("expected Grow to call shape.Shape.Shrink, got %+v", rel)
+		}
+	}
+
+	// Without types, parenthesized receivers still name the type.
+	if err := os.Remove(filepath.Join(analyzer.RepoAbs, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	analyzer.Reset()
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	for _, id := range []string{"shape.Shape.Grow", "shape.Shape.Shrink"} {
+		if !analyzer.CollectedNodeIDs[id] {
+			t.Errorf("expected a node %s without types", id)
+		}
+	}
+}
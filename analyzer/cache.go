@@ -0,0 +1,603 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// cacheEntry is the on-disk form of one repo's analysis: the results of
+// each module root, keyed by its slash-separated path relative to the repo
+// ("." for the repo root). Key covers everything besides the modules' files
+// that changes the output; an entry with another key is discarded whole.
+type cacheEntry struct {
+	Key     string                  `json:"key"`
+	Modules map[string]*moduleCache `json:"modules"`
+	// StdlibDiagnostics are the "not found" diagnostics of
+	// StdlibInterfaces, which every module contributes to.
+	StdlibDiagnostics []string `json:"stdlib_diagnostics,omitempty"`
+}
+
+// moduleCache holds what one module root contributed to the results. Hash
+// covers the module's files and those of the repo modules it depends on,
+// since its relationships resolve against their nodes.
+type moduleCache struct {
+	Hash        string                    `json:"hash"`
+	Diagnostics []string                  `json:"diagnostics,omitempty"` // From loading the module
+	Files       []fileCache               `json:"files"`
+	Overrides   []models.CallRelationship `json:"overrides,omitempty"`
+	Packages    []packageCache            `json:"packages,omitempty"`
+	PackageDocs map[string]string         `json:"package_docs,omitempty"`
+}
+
+// fileCache holds what one file contributed in the node and relationship
+// passes.
+type fileCache struct {
+	Path              string                     `json:"path"` // Relative to the repo, slash-separated
+	PkgPath           string                     `json:"pkg_path,omitempty"`
+	Nodes             []models.Node              `json:"nodes,omitempty"`
+	NodeRelationships []models.CallRelationship  `json:"node_relationships,omitempty"`
+	NodeDiagnostics   []string                   `json:"node_diagnostics,omitempty"`
+	CallRelationships []models.CallRelationship  `json:"call_relationships,omitempty"`
+	CallDiagnostics   []string                   `json:"call_diagnostics,omitempty"`
+	Imports           map[string]string          `json:"imports,omitempty"`
+	Generate          []models.GenerateDirective `json:"go_generate,omitempty"`
+}
+
+// packageCache holds a package node of PackageGraph and its imports edges.
+type packageCache struct {
+	Node    models.Node               `json:"node"`
+	Imports []models.CallRelationship `json:"imports,omitempty"`
+}
+
+// cacheRun tracks a run with CacheDir set: which modules are served from
+// the cache, and what the others add, so that it can be stored per module.
+type cacheRun struct {
+	key      string
+	roots    []string                // Module roots in loading order, absolute
+	hashes   map[string]string       // Module root relative to the repo -> hash
+	restored map[string]*moduleCache // Modules served from the cache
+	files    map[string]*fileCache   // Files of restored modules, by absolute path
+
+	stdlibDiagnostics []string // Restored with every module
+	loadDiagnostics   map[string][2]int
+	nodeSpans         map[string]fileSpans
+	callSpans         map[string]fileSpans
+	overrides         map[string][]models.CallRelationship
+}
+
+// fileSpans are the ranges of Nodes, Relationships and Diagnostics one
+// pass over a file added.
+type fileSpans struct {
+	nodes, relationships, diagnostics [2]int
+}
+
+// cachePath returns the cache file for this repo inside CacheDir.
+func (a *GoAnalyzer) cachePath() string {
+	sum := sha256.Sum256([]byte(a.RepoAbs))
+	return filepath.Join(a.CacheDir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// openCache fingerprints the run and each module and looks up the results
+// of the modules that are unchanged since they were cached. Their files
+// are then neither loaded nor collected again.
+func (a *GoAnalyzer) openCache(ctx context.Context) error {
+	key, err := a.cacheKey(ctx)
+	if err != nil {
+		return err
+	}
+	roots, hashes, err := a.moduleHashes()
+	if err != nil {
+		return err
+	}
+	run := &cacheRun{
+		key:             key,
+		roots:           roots,
+		hashes:          hashes,
+		restored:        map[string]*moduleCache{},
+		files:           map[string]*fileCache{},
+		loadDiagnostics: map[string][2]int{},
+		nodeSpans:       map[string]fileSpans{},
+		callSpans:       map[string]fileSpans{},
+		overrides:       map[string][]models.CallRelationship{},
+	}
+	a.cache = run
+
+	data, err := os.ReadFile(a.cachePath())
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil
+	}
+	for rel, hash := range hashes {
+		cached := entry.Modules[rel]
+		if cached == nil || cached.Hash != hash {
+			continue
+		}
+		run.restored[rel] = cached
+		for i := range cached.Files {
+			file := &cached.Files[i]
+			filename := filepath.Join(a.RepoAbs, filepath.FromSlash(file.Path))
+			run.files[filename] = file
+			if file.PkgPath != "" {
+				a.filePkgPaths[filename] = file.PkgPath
+			}
+		}
+	}
+	if len(run.restored) == len(roots) {
+		run.stdlibDiagnostics = entry.StdlibDiagnostics
+	}
+	a.cacheHits = len(run.restored)
+	return nil
+}
+
+// cacheKey fingerprints everything besides the modules' files that changes
+// the output: the tool version, the options, the go command's build
+// environment and, with UseGitignore, the ignore rules.
+func (a *GoAnalyzer) cacheKey(ctx context.Context) (string, error) {
+	opts := a.Options
+	opts.CacheDir = ""
+	opts.Jobs = 0
+	opts.LazySource = false
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(models.ToolVersion + "\n"))
+	h.Write(data)
+
+	// Build tags, the target platform and the toolchain decide which files
+	// are compiled and how they type-check.
+	cmd := exec.CommandContext(ctx, "go", "env", "GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED", "GOVERSION")
+	cmd.Dir = a.RepoAbs
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	env, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go env: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	h.Write(env)
+
+	if a.UseGitignore {
+		if err := a.hashIgnoreFiles(h); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashIgnoreFiles adds every .gitignore and .git/info/exclude file in the
+// repo, the files loadGitignore reads, to h.
+func (a *GoAnalyzer) hashIgnoreFiles(h io.Writer) error {
+	add := func(path string) error {
+		content, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return &ReadError{Path: path, Err: err}
+		}
+		rel, _ := filepath.Rel(a.RepoAbs, path)
+		sum := sha256.Sum256(content)
+		h.Write([]byte(filepath.ToSlash(rel) + "\x00"))
+		h.Write(sum[:])
+		return nil
+	}
+	return filepath.WalkDir(a.RepoAbs, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return &ReadError{Path: path, Err: err}
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			if err := add(filepath.Join(path, "info", "exclude")); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && d.Name() == ".gitignore" {
+			return add(path)
+		}
+		return nil
+	})
+}
+
+// moduleHashes returns the module roots loadFiles visits and a hash for
+// each, keyed by its path relative to the repo. A module's hash covers its
+// go.mod, go.sum and .go files (and vendor/modules.txt with IncludeVendor)
+// and those of the repo modules it requires or replaces to, directly or
+// not. With ResolveInterfaceDispatch or StdlibInterfaces, types are matched
+// across every module, so each hash covers the whole repo. A repo without
+// modules is hashed as a single module at its root.
+func (a *GoAnalyzer) moduleHashes() ([]string, map[string]string, error) {
+	roots, err := a.findModuleRoots()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(roots) == 0 {
+		roots = []string{a.RepoAbs}
+	}
+	isRoot := map[string]bool{}
+	for _, root := range roots {
+		isRoot[root] = true
+	}
+
+	own := map[string]string{}
+	for _, root := range roots {
+		h := sha256.New()
+		err := a.walkRepo(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return &ReadError{Path: path, Err: err}
+			}
+			if d.IsDir() {
+				// Nested modules are hashed on their own.
+				if path != root && (a.skipsDir(d.Name()) || isRoot[path]) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, _ := filepath.Rel(root, path)
+			rel = filepath.ToSlash(rel)
+			switch name := d.Name(); {
+			case filepath.Ext(name) == ".go", name == "go.mod", name == "go.sum", name == "go.work", name == "go.work.sum":
+			case rel == "vendor/modules.txt" && a.IncludeVendor:
+			default:
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return &ReadError{Path: path, Err: err}
+			}
+			sum := sha256.Sum256(content)
+			h.Write([]byte(rel + "\x00"))
+			h.Write(sum[:])
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		own[root] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	deps := a.moduleDeps(roots)
+	hashes := map[string]string{}
+	for _, root := range roots {
+		closure := map[string]bool{}
+		var visit func(root string)
+		visit = func(root string) {
+			if closure[root] {
+				return
+			}
+			closure[root] = true
+			for _, dep := range deps[root] {
+				visit(dep)
+			}
+		}
+		if a.ResolveInterfaceDispatch || len(a.StdlibInterfaces) > 0 {
+			for _, r := range roots {
+				closure[r] = true
+			}
+		} else {
+			visit(root)
+		}
+
+		h := sha256.New()
+		for _, r := range roots {
+			if closure[r] {
+				h.Write([]byte(a.cacheModule(r) + "\x00" + own[r] + "\n"))
+			}
+		}
+		hashes[a.cacheModule(root)] = hex.EncodeToString(h.Sum(nil))
+	}
+	return roots, hashes, nil
+}
+
+// moduleDeps maps each module root to the other roots its go.mod requires
+// by module path or replaces a requirement with. An unreadable go.mod has
+// no dependencies; its contents are hashed all the same.
+func (a *GoAnalyzer) moduleDeps(roots []string) map[string][]string {
+	byPath := map[string]string{}
+	for _, root := range roots {
+		if modulePath := a.modulePath(root); modulePath != "" {
+			byPath[modulePath] = root
+		}
+	}
+
+	deps := map[string][]string{}
+	for _, root := range roots {
+		gomod := filepath.Join(root, "go.mod")
+		data, err := os.ReadFile(gomod)
+		if err != nil {
+			continue
+		}
+		file, err := modfile.ParseLax(gomod, data, nil)
+		if err != nil {
+			continue
+		}
+		for _, req := range file.Require {
+			if dep, ok := byPath[req.Mod.Path]; ok && dep != root {
+				deps[root] = append(deps[root], dep)
+			}
+		}
+		for _, rep := range file.Replace {
+			if !modfile.IsDirectoryPath(rep.New.Path) {
+				continue
+			}
+			dir := rep.New.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(root, dir)
+			}
+			if dir = filepath.Clean(dir); slices.Contains(roots, dir) && dir != root {
+				deps[root] = append(deps[root], dir)
+			}
+		}
+	}
+	return deps
+}
+
+// cacheModule returns the key of module root in cacheEntry.Modules.
+func (a *GoAnalyzer) cacheModule(root string) string {
+	rel, _ := filepath.Rel(a.RepoAbs, root)
+	return filepath.ToSlash(rel)
+}
+
+// cacheRoot returns the module root of a file among those the cache
+// tracks: the innermost one containing it.
+func (a *GoAnalyzer) cacheRoot(filename string) string {
+	root := ""
+	for _, r := range a.cache.roots {
+		if isPathInRepo(r, filename) && len(r) > len(root) {
+			root = r
+		}
+	}
+	return root
+}
+
+// cachedModule returns the cached results of module root, or nil when it
+// has to be analyzed.
+func (a *GoAnalyzer) cachedModule(root string) *moduleCache {
+	if a.cache == nil {
+		return nil
+	}
+	return a.cache.restored[a.cacheModule(root)]
+}
+
+// cachedFile returns the cached results of filename, or nil when it has to
+// be analyzed.
+func (a *GoAnalyzer) cachedFile(filename string) *fileCache {
+	if a.cache == nil {
+		return nil
+	}
+	return a.cache.files[filename]
+}
+
+// restoreModule adds what loading a cached module added: its load
+// diagnostics and package docs.
+func (a *GoAnalyzer) restoreModule(cached *moduleCache) {
+	a.Diagnostics = append(a.Diagnostics, cached.Diagnostics...)
+	for key, doc := range cached.PackageDocs {
+		if a.PackageDocs == nil {
+			a.PackageDocs = map[string]string{}
+		}
+		a.PackageDocs[key] = doc
+	}
+}
+
+// restoreFileNodes adds what the node pass over a cached file added.
+func (a *GoAnalyzer) restoreFileNodes(filename string, cached *fileCache) {
+	for _, node := range cached.Nodes {
+		a.addNode(node)
+	}
+	a.Relationships = append(a.Relationships, cached.NodeRelationships...)
+	a.Diagnostics = append(a.Diagnostics, cached.NodeDiagnostics...)
+
+	relativePath, _ := filepath.Rel(a.RepoAbs, filename)
+	if a.SlashPaths {
+		relativePath = slashPath(relativePath)
+	}
+	if a.CollectImports {
+		if a.Imports == nil {
+			a.Imports = map[string]map[string]string{}
+		}
+		imports := cached.Imports
+		if imports == nil {
+			imports = map[string]string{}
+		}
+		a.Imports[relativePath] = imports
+	}
+	if len(cached.Generate) > 0 {
+		if a.Generate == nil {
+			a.Generate = map[string][]models.GenerateDirective{}
+		}
+		a.Generate[relativePath] = cached.Generate
+	}
+}
+
+// restoreFileCalls adds what the relationship pass over a cached file
+// added.
+func (a *GoAnalyzer) restoreFileCalls(cached *fileCache) {
+	a.Relationships = append(a.Relationships, cached.CallRelationships...)
+	a.Diagnostics = append(a.Diagnostics, cached.CallDiagnostics...)
+}
+
+// restoreOverrides puts the "overrides" relationships linkOverrides added
+// from start on in module order, with those of cached modules in place.
+func (a *GoAnalyzer) restoreOverrides(start int) {
+	if a.cache == nil {
+		return
+	}
+	fresh := map[string][]models.CallRelationship{}
+	for _, rel := range a.Relationships[start:] {
+		node := a.Nodes[a.nodeIndex[rel.Caller]]
+		root := a.cacheRoot(filepath.FromSlash(node.FilePath))
+		fresh[root] = append(fresh[root], rel)
+	}
+	a.Relationships = a.Relationships[:start]
+	for _, root := range a.cache.roots {
+		if cached := a.cachedModule(root); cached != nil {
+			a.Relationships = append(a.Relationships, cached.Overrides...)
+			continue
+		}
+		a.cache.overrides[root] = fresh[root]
+		a.Relationships = append(a.Relationships, fresh[root]...)
+	}
+}
+
+// cachedPackages returns the package nodes of cached modules by ID.
+func (a *GoAnalyzer) cachedPackages() map[string]*packageCache {
+	packages := map[string]*packageCache{}
+	if a.cache == nil {
+		return packages
+	}
+	for _, cached := range a.cache.restored {
+		for i := range cached.Packages {
+			packages[cached.Packages[i].Node.ID] = &cached.Packages[i]
+		}
+	}
+	return packages
+}
+
+// restoreStdlibDiagnostics reports whether every module is served from the
+// cache, leaving no types for markStdlibInterfaces to look at, and if so
+// adds the diagnostics it reported when they were cached.
+func (a *GoAnalyzer) restoreStdlibDiagnostics() bool {
+	if a.cache == nil || len(a.cache.restored) < len(a.cache.roots) {
+		return false
+	}
+	a.Diagnostics = append(a.Diagnostics, a.cache.stdlibDiagnostics...)
+	return true
+}
+
+// recordLoad notes the diagnostics loading root added from start on.
+func (a *GoAnalyzer) recordLoad(root string, start int) {
+	if a.cache != nil {
+		a.cache.loadDiagnostics[root] = [2]int{start, len(a.Diagnostics)}
+	}
+}
+
+// recordPass notes what a pass over filename added since before, which
+// holds the lengths of Nodes, Relationships and Diagnostics at its start.
+func (a *GoAnalyzer) recordPass(spans map[string]fileSpans, filename string, before [3]int) {
+	spans[filename] = fileSpans{
+		nodes:         [2]int{before[0], len(a.Nodes)},
+		relationships: [2]int{before[1], len(a.Relationships)},
+		diagnostics:   [2]int{before[2], len(a.Diagnostics)},
+	}
+}
+
+// encodeCache encodes the results of a completed run for saveCache: the
+// cached modules as they were and the others from what was recorded. It
+// runs before ExportedOnly filters the results and countFans counts them,
+// since both are redone over the restored results. stdlibStart is where
+// the diagnostics of StdlibInterfaces start.
+func (a *GoAnalyzer) encodeCache(fileInfos map[string]*fileInfo, stdlibStart int) ([]byte, error) {
+	entry := cacheEntry{
+		Key:               a.cache.key,
+		Modules:           map[string]*moduleCache{},
+		StdlibDiagnostics: a.Diagnostics[stdlibStart:],
+	}
+	for _, root := range a.cache.roots {
+		module := a.cacheModule(root)
+		if cached := a.cachedModule(root); cached != nil {
+			entry.Modules[module] = cached
+			continue
+		}
+		span := a.cache.loadDiagnostics[root]
+		entry.Modules[module] = &moduleCache{
+			Hash:        a.cache.hashes[module],
+			Diagnostics: a.Diagnostics[span[0]:span[1]],
+			Files:       []fileCache{},
+			Overrides:   a.cache.overrides[root],
+		}
+	}
+
+	filenames := make([]string, 0, len(fileInfos))
+	for filename := range fileInfos {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		module := entry.Modules[a.cacheModule(fileInfos[filename].root)]
+		relativePath, _ := filepath.Rel(a.RepoAbs, filename)
+		key := relativePath
+		if a.SlashPaths {
+			key = slashPath(relativePath)
+		}
+		nodes, calls := a.cache.nodeSpans[filename], a.cache.callSpans[filename]
+		file := fileCache{
+			Path:              filepath.ToSlash(relativePath),
+			PkgPath:           fileInfos[filename].pkgPath,
+			Nodes:             a.Nodes[nodes.nodes[0]:nodes.nodes[1]],
+			NodeRelationships: a.Relationships[nodes.relationships[0]:nodes.relationships[1]],
+			NodeDiagnostics:   a.Diagnostics[nodes.diagnostics[0]:nodes.diagnostics[1]],
+			CallRelationships: a.Relationships[calls.relationships[0]:calls.relationships[1]],
+			CallDiagnostics:   a.Diagnostics[calls.diagnostics[0]:calls.diagnostics[1]],
+			Imports:           a.Imports[key],
+			Generate:          a.Generate[key],
+		}
+		module.Files = append(module.Files, file)
+
+		pkgKey := a.packageKey(filename, fileInfos[filename])
+		if doc, ok := a.PackageDocs[pkgKey]; ok {
+			if module.PackageDocs == nil {
+				module.PackageDocs = map[string]string{}
+			}
+			module.PackageDocs[pkgKey] = doc
+		}
+	}
+
+	if a.PackageGraph {
+		cached := a.cachedPackages()
+		imports := map[string][]models.CallRelationship{}
+		for _, rel := range a.Relationships {
+			if rel.RelationshipType == "imports" {
+				imports[rel.Caller] = append(imports[rel.Caller], rel)
+			}
+		}
+		for _, node := range a.Nodes {
+			if node.ComponentType != "package" || cached[node.ID] != nil {
+				continue
+			}
+			module := entry.Modules[a.cacheModule(a.cacheRoot(filepath.FromSlash(node.FilePath)))]
+			module.Packages = append(module.Packages, packageCache{Node: node, Imports: imports[node.ID]})
+		}
+	}
+	return json.Marshal(entry)
+}
+
+// saveCache writes an entry from encodeCache for openCache to find next
+// time.
+func (a *GoAnalyzer) saveCache(data []byte) error {
+	if err := os.MkdirAll(a.CacheDir, 0755); err != nil {
+		return err
+	}
+	// Write to a temp file first so a concurrent run never reads a
+	// truncated entry.
+	tmp, err := os.CreateTemp(a.CacheDir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), a.cachePath())
+}
@@ -0,0 +1,175 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAnalyzeCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	cacheDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	writeFiles(t, tmpDir, map[string]string{
+		"sub/go.mod": "module example.com/sub\n\ngo 1.25\n",
+		"main.go":    "package main\n\nfunc main() { helper() }\n\nfunc helper() {}\n",
+		"sub/sub.go": "package sub\n\nfunc Sub() {}\n",
+	})
+
+	run := func() *GoAnalyzer {
+		analyzer, err := NewGoAnalyzer(tmpDir)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		analyzer.CacheDir = cacheDir
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		return analyzer
+	}
+
+	first := run()
+	if first.cacheHits != 0 {
+		t.Fatalf("Expected the first run to miss the cache")
+	}
+	second := run()
+	if second.cacheHits != 2 {
+		t.Fatalf("Expected the second run to serve both modules from the cache, got %d", second.cacheHits)
+	}
+	if len(second.Nodes) != len(first.Nodes) || len(second.Relationships) != len(first.Relationships) {
+		t.Errorf("Expected cached results to match: %d/%d nodes, %d/%d relationships",
+			len(second.Nodes), len(first.Nodes), len(second.Relationships), len(first.Relationships))
+	}
+	if !second.CollectedNodeIDs["main.helper"] {
+		t.Errorf("Expected cached node IDs to be restored, got %v", second.CollectedNodeIDs)
+	}
+
+	// Changing a file in the nested module only invalidates that module.
+	writeFiles(t, tmpDir, map[string]string{"sub/sub.go": "package sub\n\nfunc Sub() {}\n\nfunc Added() {}\n"})
+	third := run()
+	if third.cacheHits != 1 {
+		t.Errorf("Expected only the unchanged root module to be served from the cache, got %d", third.cacheHits)
+	}
+	if !third.CollectedNodeIDs["sub.sub.Added"] || !third.CollectedNodeIDs["main.helper"] {
+		t.Errorf("Expected the re-analysis to pick up sub.sub.Added next to the cached main.helper, got %v", third.CollectedNodeIDs)
+	}
+
+	// Different options do not reuse each other's results.
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.CacheDir = cacheDir
+	analyzer.IncludeSource = false
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if analyzer.cacheHits != 0 {
+		t.Errorf("Expected different options to miss the cache")
+	}
+	for _, node := range analyzer.Nodes {
+		if node.SourceCode != "" {
+			t.Errorf("Expected no source for %s", node.ID)
+		}
+	}
+}
+
+func TestAnalyzeCacheMatchesFreshRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	cacheDir := t.TempDir()
+	// The root module depends on sub through a replace; tools stands alone.
+	writeFiles(t, tmpDir, map[string]string{
+		"go.mod":          "module example.com/test\n\ngo 1.25\n\nrequire example.com/sub v0.0.0\n\nreplace example.com/sub => ./sub\n",
+		"main.go":         "// Package main is the entry point.\npackage main\n\nimport \"example.com/sub\"\n\n//go:generate echo hi\nfunc main() { sub.Sub(); go func() {}() }\n",
+		"sub/go.mod":      "module example.com/sub\n\ngo 1.25\n",
+		"sub/sub.go":      "package sub\n\ntype Base struct{}\n\nfunc (Base) Run() {}\n\ntype Job struct{ Base }\n\nfunc (Job) Run() {}\n\nfunc Sub() {}\n",
+		"tools/go.mod":    "module example.com/tools\n\ngo 1.25\n",
+		"tools/tool.go":   "package tools\n\nfunc Tool() {}\n",
+		"tools/broken.go": "package tools\n\n}\n",
+	})
+
+	run := func(cacheDir string) ([]byte, int) {
+		t.Helper()
+		analyzer, err := NewGoAnalyzer(tmpDir)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		analyzer.CacheDir = cacheDir
+		analyzer.PackageGraph = true
+		analyzer.CollectImports = true
+		analyzer.CollectPackageDocs = true
+		analyzer.CollectGenerate = true
+		analyzer.ExtractClosures = true
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		data, err := json.Marshal(analyzer.Result())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data, analyzer.cacheHits
+	}
+	check := func(step string, wantHits int) {
+		t.Helper()
+		cached, hits := run(cacheDir)
+		if hits != wantHits {
+			t.Errorf("%s: expected %d modules from the cache, got %d", step, wantHits, hits)
+		}
+		if fresh, _ := run(""); string(cached) != string(fresh) {
+			t.Errorf("%s: cached results differ from a fresh run:\ncached: %s\nfresh:  %s", step, cached, fresh)
+		}
+	}
+
+	check("first run", 0)
+	check("unchanged", 3)
+	writeFiles(t, tmpDir, map[string]string{"tools/tool.go": "package tools\n\nfunc Tool() {}\n\nfunc More() { Tool() }\n"})
+	check("tools changed", 2)
+	// The root module resolves calls against sub, so it is analyzed again
+	// with it.
+	writeFiles(t, tmpDir, map[string]string{"sub/sub.go": "package sub\n\nfunc Sub() { Other() }\n\nfunc Other() {}\n"})
+	check("sub changed", 1)
+}
+
+func TestAnalyzeCacheKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	cacheDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	writeFiles(t, tmpDir, map[string]string{
+		"main.go":    "package main\n\nfunc main() {}\n",
+		"gen/gen.go": "package gen\n\nfunc G() {}\n",
+		"tag/tag.go": "//go:build foo\n\npackage tag\n\nfunc Tagged() {}\n",
+		".gitignore": "*.tmp\n",
+	})
+
+	run := func() *GoAnalyzer {
+		t.Helper()
+		analyzer, err := NewGoAnalyzer(tmpDir)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		analyzer.CacheDir = cacheDir
+		analyzer.UseGitignore = true
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		return analyzer
+	}
+
+	if first := run(); !first.CollectedNodeIDs["gen.gen.G"] || first.CollectedNodeIDs["tag.tag.Tagged"] {
+		t.Fatalf("Expected gen.gen.G and no tag.tag.Tagged, got %v", first.CollectedNodeIDs)
+	}
+	if second := run(); second.cacheHits != 1 {
+		t.Fatalf("Expected the second run to be served from the cache")
+	}
+
+	// A new ignore rule changes which files are analyzed.
+	writeFiles(t, tmpDir, map[string]string{".gitignore": "*.tmp\ngen/\n"})
+	if analyzer := run(); analyzer.cacheHits != 0 || analyzer.CollectedNodeIDs["gen.gen.G"] {
+		t.Errorf("Expected the .gitignore change to miss the cache and drop gen.gen.G, got %d hits and %v", analyzer.cacheHits, analyzer.CollectedNodeIDs)
+	}
+
+	// So do build tags passed through GOFLAGS.
+	t.Setenv("GOFLAGS", "-mod=mod -tags=foo")
+	if analyzer := run(); analyzer.cacheHits != 0 || !analyzer.CollectedNodeIDs["tag.tag.Tagged"] {
+		t.Errorf("Expected the build tag to miss the cache and add tag.tag.Tagged, got %d hits and %v", analyzer.cacheHits, analyzer.CollectedNodeIDs)
+	}
+}
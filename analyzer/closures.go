@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// collectClosures emits a node for each function literal directly inside
+// roots, numbered by order of appearance as enclosingID$func1, $func2, ...
+// Literals nested in a literal are numbered relative to that literal.
+func (a *GoAnalyzer) collectClosures(enclosingID string, filePath string, source *fileSource, roots ...ast.Node) {
+	count := 0
+	for _, root := range roots {
+		ast.Inspect(root, func(n ast.Node) bool {
+			lit, ok := n.(*ast.FuncLit)
+			if !ok {
+				return true
+			}
+			count++
+			closureID := fmt.Sprintf("%s$func%d", enclosingID, count)
+			a.visitFuncLit(lit, closureID, fmt.Sprintf("func%d", count), filePath, source)
+			a.collectClosures(closureID, filePath, source, lit.Body)
+			return false
+		})
+	}
+}
+
+func (a *GoAnalyzer) visitFuncLit(lit *ast.FuncLit, closureID string, name string, filePath string, source *fileSource) {
+	relativePath, _ := filepath.Rel(a.RepoAbs, filePath)
+	startPos := a.FileSet.Position(lit.Pos())
+	endPos := a.FileSet.Position(lit.End())
+
+	var sourceCode, hash string
+	if src, ok := a.nodeSpan(source, filePath, closureID, startPos.Offset, endPos.Offset); ok {
+		hash = contentHash(src)
+		if a.IncludeSource {
+			sourceCode = string(src)
+		}
+	}
+
+	a.closureIDs[lit] = closureID
+	a.addNode(models.Node{
+		ID:            closureID,
+		Name:          name,
+		ComponentType: "closure",
+		FilePath:      filePath,
+		RelativePath:  relativePath,
+		StartLine:     startPos.Line,
+		EndLine:       endPos.Line,
+		StartCol:      startPos.Column,
+		EndCol:        endPos.Column,
+		StartByte:     startPos.Offset,
+		EndByte:       endPos.Offset,
+		NodeType:      "closure",
+		ComponentID:   closureID,
+		DisplayName:   "closure " + closureID,
+		DependsOn:     []string{},
+		SourceCode:    sourceCode,
+		ContentHash:   hash,
+		Parameters:    paramNames(lit.Type),
+	})
+}
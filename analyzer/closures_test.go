@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractClosures(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	content := `package testpkg
+
+func Save() {}
+
+func Run(items []int) {
+	each := func(i int) {
+		Save()
+	}
+	for _, i := range items {
+		each(i)
+	}
+	go func() {
+		defer func() { Save() }()
+	}()
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "run.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.ExtractClosures = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	closures := map[string]bool{}
+	for _, node := range analyzer.Nodes {
+		if node.ComponentType == "closure" {
+			closures[node.ID] = true
+		}
+	}
+	for _, id := range []string{"run.Run$func1", "run.Run$func2", "run.Run$func2$func1"} {
+		if !closures[id] {
+			t.Errorf("Expected closure node %s, got %v", id, closures)
+		}
+	}
+
+	callers := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		if rel.Callee == "run.Save" {
+			callers[rel.Caller] = rel.IsResolved
+		}
+	}
+	if resolved, ok := callers["run.Run$func1"]; !ok || !resolved {
+		t.Errorf("Expected resolved edge run.Run$func1 -> run.Save, got %v", callers)
+	}
+	if _, ok := callers["run.Run$func2$func1"]; !ok {
+		t.Errorf("Expected nested closure edge to run.Save, got %v", callers)
+	}
+	if _, ok := callers["run.Run"]; ok {
+		t.Error("Expected calls inside closures not to be attributed to run.Run")
+	}
+}
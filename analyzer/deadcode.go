@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// DeadCodeCandidates returns the sorted IDs of functions and methods that no
+// resolved "calls" or "dynamic_call" relationship points at. main, init and
+// the "<init>" nodes are never reported, and exported declarations only with
+// DeadCodeExported set.
+//
+// This is a heuristic: functions used as values, called via reflection or
+// from outside the analyzed packages, or only reached through an interface
+// without ResolveInterfaceDispatch are reported even though they are live.
+func (a *GoAnalyzer) DeadCodeCandidates() []string {
+	return DeadCode(a.Nodes, a.Relationships, a.DeadCodeExported)
+}
+
+// DeadCode is DeadCodeCandidates over nodes and relationships of an earlier
+// result, such as one from AnalyzeGitRef, reporting exported declarations
+// when exported is set.
+func DeadCode(nodes []models.Node, relationships []models.CallRelationship, exported bool) []string {
+	referenced := map[string]bool{}
+	for _, rel := range relationships {
+		if rel.IsResolved && isCallEdge(rel) {
+			referenced[rel.Callee] = true
+		}
+	}
+
+	candidates := []string{}
+	for _, node := range nodes {
+		if node.ComponentType != "function" && node.ComponentType != "method" {
+			continue
+		}
+		if node.ComponentType == "function" && (node.Name == "main" || node.Name == "init" || node.Name == varInitName) {
+			continue
+		}
+		if node.Exported && !exported {
+			continue
+		}
+		if !referenced[node.ID] {
+			candidates = append(candidates, node.ID)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
@@ -0,0 +1,40 @@
+package analyzer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDeadCodeCandidates(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"dead.go": `package main
+
+type store struct{}
+
+func (s *store) flush() {}
+
+func used() {}
+
+func unused() {}
+
+func Exported() {}
+
+func init() { used() }
+
+func main() {}
+`})
+
+	if got, want := analyzer.DeadCodeCandidates(), []string{"dead.store.flush", "dead.unused"}; !slices.Equal(got, want) {
+		t.Errorf("expected candidates %v, got %v", want, got)
+	}
+
+	analyzer.DeadCodeExported = true
+	if got, want := analyzer.DeadCodeCandidates(), []string{"dead.Exported", "dead.store.flush", "dead.unused"}; !slices.Equal(got, want) {
+		t.Errorf("with DeadCodeExported, expected candidates %v, got %v", want, got)
+	}
+
+	// DeadCode works the same from a result.
+	result := analyzer.Result()
+	if got, want := DeadCode(result.Nodes, result.CallRelationships, false), []string{"dead.store.flush", "dead.unused"}; !slices.Equal(got, want) {
+		t.Errorf("from the result, expected candidates %v, got %v", want, got)
+	}
+}
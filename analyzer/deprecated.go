@@ -0,0 +1,16 @@
+package analyzer
+
+import "strings"
+
+// deprecationNote returns the text of the "Deprecated:" paragraph of a doc
+// comment, as the Go convention marks deprecated API, joined into one line.
+// ok is false when there is no such paragraph.
+func deprecationNote(doc string) (note string, ok bool) {
+	for _, paragraph := range strings.Split(doc, "\n\n") {
+		rest, found := strings.CutPrefix(strings.TrimLeft(paragraph, "\n"), "Deprecated:")
+		if found {
+			return strings.Join(strings.Fields(rest), " "), true
+		}
+	}
+	return "", false
+}
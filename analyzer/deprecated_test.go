@@ -0,0 +1,36 @@
+package analyzer
+
+import "testing"
+
+func TestDeprecatedNodes(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"api.go": `package testpkg
+
+// Fetch gets the resource.
+//
+// Deprecated: use FetchContext instead, which
+// honors cancellation.
+func Fetch() {}
+
+// FetchContext gets the resource. It is not Deprecated: the word only
+// counts at the start of a paragraph.
+func FetchContext() {}
+
+// Deprecated: Client is replaced by Session.
+type Client struct{}
+`})
+
+	want := map[string]string{
+		"api.Fetch":        "use FetchContext instead, which honors cancellation.",
+		"api.FetchContext": "",
+		"api.Client":       "Client is replaced by Session.",
+	}
+	for _, node := range analyzer.Nodes {
+		note, ok := want[node.ID]
+		if !ok {
+			continue
+		}
+		if node.Deprecated != (note != "") || node.DeprecationNote != note {
+			t.Errorf("%s: expected deprecated=%v with note %q, got %v with %q", node.ID, note != "", note, node.Deprecated, node.DeprecationNote)
+		}
+	}
+}
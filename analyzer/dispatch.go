@@ -0,0 +1,78 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// collectDynamicCalls emits a "dynamic_call" edge to each repo-local
+// implementation of a method called through a repo-local interface value.
+func (a *GoAnalyzer) collectDynamicCalls(callerID string, call *ast.CallExpr, typeInfo *types.Info) {
+	fun, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || !a.emits("dynamic_call") {
+		return
+	}
+	sel := typeInfo.Selections[fun]
+	if sel == nil || sel.Kind() != types.MethodVal {
+		return
+	}
+	named, ok := sel.Recv().(*types.Named)
+	if !ok || !a.isPosInRepo(named.Obj().Pos()) {
+		return
+	}
+	iface, ok := named.Underlying().(*types.Interface)
+	if !ok {
+		return
+	}
+
+	methodName := sel.Obj().Name()
+	for _, typeName := range a.repoTypeNames() {
+		if types.IsInterface(typeName.Type()) {
+			continue
+		}
+		// Pointer receivers widen the method set, so check both forms.
+		candidate := typeName.Type()
+		if !types.Implements(candidate, iface) {
+			candidate = types.NewPointer(candidate)
+			if !types.Implements(candidate, iface) {
+				continue
+			}
+		}
+		obj, _, _ := types.LookupFieldOrMethod(candidate, true, sel.Obj().Pkg(), methodName)
+		fn, ok := obj.(*types.Func)
+		if !ok || !a.isPosInRepo(fn.Pos()) {
+			continue
+		}
+		calleeName := a.getComponentIDForPos(fn.Pos(), fn.Name(), receiverTypeString(fn.Type()))
+		a.Relationships = append(a.Relationships, models.CallRelationship{
+			Caller:           callerID,
+			Callee:           calleeName,
+			CallLine:         a.FileSet.Position(call.Pos()).Line,
+			RelationshipType: "dynamic_call",
+			IsResolved:       a.CollectedNodeIDs[calleeName],
+			Confidence:       models.ConfidenceHeuristic, // One of the possible targets
+		})
+	}
+}
+
+// repoTypeNames returns the package-level named types declared in the repo's
+// type-checked packages.
+func (a *GoAnalyzer) repoTypeNames() []*types.TypeName {
+	if a.localTypes != nil {
+		return a.localTypes
+	}
+	a.localTypes = []*types.TypeName{}
+	for _, pkg := range a.localPackages {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() || !a.isPosInRepo(typeName.Pos()) {
+				continue
+			}
+			a.localTypes = append(a.localTypes, typeName)
+		}
+	}
+	return a.localTypes
+}
@@ -0,0 +1,120 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+func TestResolveInterfaceDispatch(t *testing.T) {
+	content := `package testpkg
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct{ side float64 }
+
+func (s Square) Area() float64 { return s.side * s.side }
+
+type Circle struct{ r float64 }
+
+func (c *Circle) Area() float64 { return 3 * c.r * c.r }
+
+type Label struct{}
+
+func Total(shapes []Shape) float64 {
+	sum := 0.0
+	for _, s := range shapes {
+		sum += s.Area()
+	}
+	return sum
+}
+`
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, "shapes.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dynamicCallees := func(enabled bool) []string {
+		analyzer, err := NewGoAnalyzer(tmpDir)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		analyzer.ResolveInterfaceDispatch = enabled
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		callees := []string{}
+		for _, rel := range analyzer.Relationships {
+			if rel.RelationshipType != "dynamic_call" {
+				continue
+			}
+			if rel.Caller != "shapes.Total" || !rel.IsResolved {
+				t.Errorf("Unexpected dynamic edge %+v", rel)
+			}
+			callees = append(callees, rel.Callee)
+		}
+		sort.Strings(callees)
+		return callees
+	}
+
+	if got := dynamicCallees(false); len(got) != 0 {
+		t.Errorf("Expected no dynamic edges when disabled, got %v", got)
+	}
+	got := dynamicCallees(true)
+	want := []string{"shapes.Circle.Area", "shapes.Square.Area"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected dynamic edges to %v, got %v", want, got)
+	}
+}
+
+func TestInterfaceMethodCallOnReturnedValue(t *testing.T) {
+	// The static type of open() is the interface, so the chained call
+	// names the interface method like a call on an interface variable.
+	// Interface methods have no nodes, so the edge stays unresolved;
+	// dispatch resolves it to the implementation.
+	analyzer := analyzeFiles(t, map[string]string{"io.go": `package testpkg
+
+type Reader interface{ Read() string }
+
+type file struct{}
+
+func (file) Read() string { return "" }
+
+func open() Reader { return file{} }
+
+func Direct(r Reader) string { return r.Read() }
+
+func Chained() string { return open().Read() }
+`})
+	analyzer.Reset()
+	analyzer.ResolveInterfaceDispatch = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	edges := func(caller string) map[string]models.CallRelationship {
+		found := map[string]models.CallRelationship{}
+		for _, rel := range analyzer.Relationships {
+			if rel.Caller == caller {
+				found[rel.RelationshipType+" "+rel.Callee] = rel
+			}
+		}
+		return found
+	}
+	for _, caller := range []string{"io.Direct", "io.Chained"} {
+		found := edges(caller)
+		call, ok := found["calls io.Reader.Read"]
+		if !ok || call.IsResolved || call.Confidence != models.ConfidenceExact {
+			t.Errorf("%s: expected an exact, unresolved call to io.Reader.Read, got %v", caller, found)
+		}
+		if dynamic, ok := found["dynamic_call io.file.Read"]; !ok || !dynamic.IsResolved {
+			t.Errorf("%s: expected a resolved dynamic_call to io.file.Read, got %v", caller, found)
+		}
+	}
+}
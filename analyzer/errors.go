@@ -0,0 +1,47 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoPackages is returned when the Patterns or Files given to the analyzer
+// select no Go files in the repo.
+var ErrNoPackages = errors.New("no Go packages matched")
+
+// ErrAlreadyAnalyzed is returned when Analyze or AnalyzeContext is called
+// again without a Reset in between, which would mix two runs' results.
+var ErrAlreadyAnalyzed = errors.New("analyzer already ran; call Reset before analyzing again")
+
+// ReadError reports a file or directory in the repo that could not be read.
+// It unwraps to the underlying error, so errors.Is(err, fs.ErrNotExist)
+// detects a missing repo path.
+type ReadError struct {
+	Path string
+	Err  error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("reading %s: %v", e.Path, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// LoadError reports that the go command failed to load packages from a
+// module root.
+type LoadError struct {
+	Dir      string
+	Patterns []string
+	Err      error
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("loading %s in %s: %v", strings.Join(e.Patterns, " "), e.Dir, e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// collectFuncAliases records each package-level variable of function type
+// whose initializer is a plain reference to a package-level function, such
+// as var Log = log.Printf, so that calls through the variable can be linked
+// to the function it starts out holding. Variables initialized any other
+// way, e.g. with a closure or a method value, are left out.
+func (a *GoAnalyzer) collectFuncAliases(filenames []string, fileInfos map[string]*fileInfo) {
+	for _, filename := range filenames {
+		info := fileInfos[filename]
+		if info.info == nil {
+			continue
+		}
+		for _, decl := range info.file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				spec := spec.(*ast.ValueSpec)
+				if len(spec.Values) != len(spec.Names) {
+					continue
+				}
+				for i, name := range spec.Names {
+					v, ok := info.info.Defs[name].(*types.Var)
+					if !ok {
+						continue
+					}
+					if _, ok := v.Type().Underlying().(*types.Signature); !ok {
+						continue
+					}
+					if fn := funcReference(spec.Values[i], info.info); fn != nil {
+						a.funcAliases[v] = fn
+					}
+				}
+			}
+		}
+	}
+}
+
+// funcReference returns the package-level function expr names, as in "F"
+// or "pkg.F", or nil if expr is anything else.
+func funcReference(expr ast.Expr, info *types.Info) *types.Func {
+	var ident *ast.Ident
+	switch x := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		ident = x
+	case *ast.SelectorExpr:
+		if info.Selections[x] != nil {
+			return nil
+		}
+		ident = x.Sel
+	default:
+		return nil
+	}
+	fn, ok := info.Uses[ident].(*types.Func)
+	if !ok || fn.Type().(*types.Signature).Recv() != nil {
+		return nil
+	}
+	return fn
+}
@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+func TestAnalyzeFuncAliasCalls(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/test/util"
+)
+
+var Log = logf
+
+var Printf = fmt.Printf
+
+var handler = func() {}
+
+func logf(format string, args ...any) {}
+
+func main() {
+	Log("x")
+	Printf("y")
+	handler()
+	util.Warn()
+}
+`,
+		"util/util.go": `package util
+
+var Warn = (warn)
+
+func warn() {}
+`,
+	})
+
+	calls := map[string]models.CallRelationship{}
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "main.main" {
+			calls[rel.Callee] = rel
+		}
+	}
+	for callee, want := range map[string]bool{"main.logf": true, "fmt.Printf": false, "util.util.warn": true} {
+		rel, ok := calls[callee]
+		if !ok {
+			t.Errorf("expected a call to %s, got %v", callee, calls)
+			continue
+		}
+		if rel.IsResolved != want || rel.Confidence != models.ConfidenceHeuristic {
+			t.Errorf("%s: expected resolved=%v with heuristic confidence, got %+v", callee, want, rel)
+		}
+	}
+	if rel, ok := calls["main.handler"]; !ok || rel.IsResolved {
+		t.Errorf("expected an unresolved call named after the closure-valued var, got %v", calls)
+	}
+}
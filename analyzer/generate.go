@@ -0,0 +1,52 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// collectGenerate records the file's //go:generate directives in Generate.
+// Like the go command, only comments starting a line count. first is the
+// index of the file's first node, for linking each directive to the
+// top-level declaration following it.
+func (a *GoAnalyzer) collectGenerate(filePath string, info *fileInfo, first int) {
+	var directives []models.GenerateDirective
+	for _, group := range info.file.Comments {
+		for _, c := range group.List {
+			pos := a.FileSet.Position(c.Pos())
+			if pos.Column != 1 || !isGenerateDirective(c.Text) {
+				continue
+			}
+			directive := models.GenerateDirective{Directive: c.Text, Line: pos.Line}
+			next := 0
+			for _, node := range a.Nodes[first:] {
+				if node.EnclosingFunc == "" && node.NodeType != "closure" && node.StartLine > pos.Line && (next == 0 || node.StartLine < next) {
+					next = node.StartLine
+					directive.Before = node.ID
+				}
+			}
+			directives = append(directives, directive)
+		}
+	}
+	if len(directives) == 0 {
+		return
+	}
+
+	if a.Generate == nil {
+		a.Generate = map[string][]models.GenerateDirective{}
+	}
+	relativePath, _ := filepath.Rel(a.RepoAbs, filePath)
+	if a.SlashPaths {
+		relativePath = slashPath(relativePath)
+	}
+	a.Generate[relativePath] = directives
+}
+
+// isGenerateDirective reports whether the comment text is a //go:generate
+// directive: the prefix followed by a space or tab.
+func isGenerateDirective(text string) bool {
+	rest, ok := strings.CutPrefix(text, "//go:generate")
+	return ok && (strings.HasPrefix(rest, " ") || strings.HasPrefix(rest, "\t"))
+}
@@ -0,0 +1,44 @@
+package analyzer
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+func TestGenerateDirectives(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"kind.go": `package testpkg
+
+//go:generate stringer -type=Kind
+//go:generate	go run gen.go -out kind_gen.go
+
+// Kind is documented.
+type Kind struct{}
+
+func Use() {
+	//go:generate not-at-column-one
+}
+
+//go:generatex not a directive
+`})
+	if analyzer.Generate != nil {
+		t.Errorf("expected no directives without CollectGenerate, got %v", analyzer.Generate)
+	}
+
+	analyzer.Reset()
+	analyzer.CollectGenerate = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	want := []models.GenerateDirective{
+		{Directive: "//go:generate stringer -type=Kind", Line: 3, Before: "kind.Kind"},
+		{Directive: "//go:generate\tgo run gen.go -out kind_gen.go", Line: 4, Before: "kind.Kind"},
+	}
+	if got := analyzer.Generate["kind.go"]; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := analyzer.Result().Generate; len(got) != 1 {
+		t.Errorf("expected directives for one file in the result, got %v", got)
+	}
+}
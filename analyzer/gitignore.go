@@ -0,0 +1,33 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// loadGitignore reads the .gitignore files under the repo root (nested ones
+// apply to their own directory) for isIgnored.
+func (a *GoAnalyzer) loadGitignore() error {
+	patterns, err := gitignore.ReadPatterns(osfs.New(a.RepoAbs), nil)
+	if err != nil {
+		return err
+	}
+	a.ignore = gitignore.NewMatcher(patterns)
+	return nil
+}
+
+// isIgnored reports whether path is excluded by the repo's .gitignore
+// rules. It is always false unless UseGitignore is set.
+func (a *GoAnalyzer) isIgnored(path string, isDir bool) bool {
+	if a.ignore == nil {
+		return false
+	}
+	rel, err := filepath.Rel(a.RepoAbs, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return a.ignore.Match(strings.Split(filepath.ToSlash(rel), "/"), isDir)
+}
@@ -0,0 +1,157 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// AnalyzeGitRef analyzes repoPath as of ref, a branch, tag, commit hash or
+// other revision such as "HEAD~2", without checking it out: the tree is
+// read from the git repository containing repoPath, without a git binary,
+// written to a temporary directory, analyzed there with an analyzer
+// configured by opts, and removed. Node file paths are reported under
+// repoPath, as if ref were checked out, and GitCommit is the commit ref
+// names. Only the files analysis reads are written: Go sources, go.mod,
+// go.sum, go.work, .gitignore files and vendor/modules.txt. CacheDir is
+// cleared: results for a tree that is gone once AnalyzeGitRef returns are
+// not worth keeping.
+func AnalyzeGitRef(repoPath, ref string, opts ...AnalyzerOption) (*models.AnalysisResult, error) {
+	return AnalyzeGitRefContext(context.Background(), repoPath, ref, opts...)
+}
+
+// AnalyzeGitRefContext is like AnalyzeGitRef but stops when ctx is done,
+// as AnalyzeContext does. The partial result is returned along with
+// ctx.Err().
+func AnalyzeGitRefContext(ctx context.Context, repoPath, ref string, opts ...AnalyzerOption) (*models.AnalysisResult, error) {
+	repoAbs, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := git.PlainOpenWithOptions(repoAbs, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, &ReadError{Path: repoAbs, Err: err}
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, fmt.Errorf("resolving %q: %w", ref, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %q: %w", ref, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	// For a repo path below the top of the worktree, only its subtree is
+	// analyzed.
+	if worktree, err := repo.Worktree(); err == nil {
+		if rel, err := filepath.Rel(worktree.Filesystem.Root(), repoAbs); err == nil && rel != "." {
+			if tree, err = tree.Tree(filepath.ToSlash(rel)); err != nil {
+				return nil, fmt.Errorf("%s at %s: %w", filepath.ToSlash(rel), ref, err)
+			}
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "codewiki-go-analyzer-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := writeTree(tree, dir); err != nil {
+		return nil, err
+	}
+
+	a, err := NewGoAnalyzer(dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	a.CacheDir = ""
+	err = a.AnalyzeContext(ctx)
+	if err != nil && !a.Partial {
+		return nil, err
+	}
+	prefixes := [][2]string{{dir, repoAbs}, {filepath.ToSlash(dir), filepath.ToSlash(repoAbs)}}
+	for i := range a.Nodes {
+		for _, prefix := range prefixes {
+			if rest, ok := strings.CutPrefix(a.Nodes[i].FilePath, prefix[0]); ok {
+				a.Nodes[i].FilePath = prefix[1] + rest
+				break
+			}
+		}
+	}
+	// Diagnostics such as load errors name files by absolute path.
+	for i := range a.Diagnostics {
+		for _, prefix := range prefixes {
+			a.Diagnostics[i] = strings.ReplaceAll(a.Diagnostics[i], prefix[0], prefix[1])
+		}
+	}
+	result := a.Result()
+	result.GitCommit = hash.String()
+	return result, err
+}
+
+// writeTree writes the regular files of tree that analysis reads below dir.
+func writeTree(tree *object.Tree, dir string) error {
+	return tree.Files().ForEach(func(f *object.File) error {
+		if f.Mode != filemode.Regular && f.Mode != filemode.Executable {
+			return nil
+		}
+		if !analyzedTreeFile(f.Name) {
+			return nil
+		}
+		path, err := treePath(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		r, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		w, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	})
+}
+
+// treePath is where tree entry name is written under dir. Entry names come
+// from the repository, so one that would land outside dir is an error.
+func treePath(dir, name string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if path == filepath.Clean(dir) || !isPathInRepo(dir, path) {
+		return "", fmt.Errorf("tree entry %q is outside the tree", name)
+	}
+	return path, nil
+}
+
+// analyzedTreeFile reports whether name, a slash path in a git tree, is one
+// of the files analysis reads.
+func analyzedTreeFile(name string) bool {
+	switch path.Base(name) {
+	case "go.mod", "go.sum", "go.work", ".gitignore":
+		return true
+	}
+	return path.Ext(name) == ".go" || name == "vendor/modules.txt" || strings.HasSuffix(name, "/vendor/modules.txt")
+}
@@ -0,0 +1,138 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestAnalyzeGitRef(t *testing.T) {
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(files map[string]string) string {
+		t.Helper()
+		writeFiles(t, repoDir, files)
+		for name := range files {
+			if _, err := worktree.Add(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(0, 0)}
+		hash, err := worktree.Commit("commit", &git.CommitOptions{Author: signature})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash.String()
+	}
+	first := commit(map[string]string{
+		"go.mod":  "module example.com/test\n\ngo 1.25\n",
+		"main.go": "package main\n\nfunc main() { old() }\n\nfunc old() {}\n",
+	})
+	head := commit(map[string]string{
+		"main.go": "package main\n\nfunc main() { current() }\n\nfunc current() {}\n",
+	})
+	// An uncommitted change is not seen by either ref.
+	if err := os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for ref, want := range map[string]struct{ commit, callee string }{
+		"HEAD":   {head, "main.current"},
+		"HEAD~1": {first, "main.old"},
+		first:    {first, "main.old"},
+	} {
+		result, err := AnalyzeGitRef(repoDir, ref)
+		if err != nil {
+			t.Fatalf("%s: AnalyzeGitRef failed: %v", ref, err)
+		}
+		if result.GitCommit != want.commit {
+			t.Errorf("%s: expected GitCommit %s, got %s", ref, want.commit, result.GitCommit)
+		}
+		if len(result.CallRelationships) != 1 || result.CallRelationships[0].Callee != want.callee || !result.CallRelationships[0].IsResolved {
+			t.Errorf("%s: expected a resolved call to %s, got %+v", ref, want.callee, result.CallRelationships)
+		}
+		for _, node := range result.Nodes {
+			if node.FilePath != filepath.ToSlash(filepath.Join(repoDir, "main.go")) {
+				t.Errorf("%s: expected %s under the repo, got %s", ref, node.ID, node.FilePath)
+			}
+		}
+	}
+
+	// Without go.mod, the diagnostic names the repo rather than the
+	// temporary directory the tree was written to, and CacheDir is ignored.
+	if _, err := worktree.Remove("go.mod"); err != nil {
+		t.Fatal(err)
+	}
+	commit(nil)
+	cacheDir := t.TempDir()
+	result, err := AnalyzeGitRef(repoDir, "HEAD", func(a *GoAnalyzer) { a.CacheDir = cacheDir })
+	if err != nil {
+		t.Fatalf("AnalyzeGitRef failed: %v", err)
+	}
+	if len(result.Diagnostics) != 1 || !strings.Contains(result.Diagnostics[0], repoDir) {
+		t.Errorf("expected a no-module diagnostic naming %s, got %q", repoDir, result.Diagnostics)
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+		t.Errorf("expected nothing cached, got %v", entries)
+	}
+
+	if _, err := AnalyzeGitRef(repoDir, "no-such-branch"); err == nil {
+		t.Error("expected an error for an unknown ref")
+	}
+}
+
+func TestAnalyzedTreeFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"main.go":                true,
+		"cmd/tool/go.mod":        true,
+		"go.sum":                 true,
+		"go.work":                true,
+		"internal/.gitignore":    true,
+		"vendor/modules.txt":     true,
+		"sub/vendor/modules.txt": true,
+		"README.md":              false,
+		"testdata/big.bin":       false,
+		"modules.txt":            false,
+		"docs/go.mod.tmpl":       false,
+	} {
+		if got := analyzedTreeFile(name); got != want {
+			t.Errorf("analyzedTreeFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestTreePath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tree")
+	for name, want := range map[string]string{
+		"main.go":         filepath.Join(dir, "main.go"),
+		"cmd/tool/go.mod": filepath.Join(dir, "cmd", "tool", "go.mod"),
+		"a/../b.go":       filepath.Join(dir, "b.go"),
+		"../evil.go":      "",
+		"a/../../evil.go": "",
+		"..":              "",
+		".":               "",
+	} {
+		got, err := treePath(dir, name)
+		if want == "" {
+			if err == nil {
+				t.Errorf("treePath(%q) = %q, want an error", name, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("treePath(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// contentHash returns a sha256 hex digest of src that ignores formatting.
+// src is split into Go tokens (comments included) and the tokens are
+// joined with single spaces, so any change in indentation, line breaks or
+// spacing between tokens hashes the same, while changing, adding or
+// removing a token does not. Semicolons the scanner inserts at line ends
+// are dropped so that splitting a statement block across lines is also
+// formatting-only. Whitespace inside string literals and comments is
+// part of the token and is kept.
+func contentHash(src []byte) string {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	// Errors (e.g. a span that is not a complete construct) still leave
+	// usable tokens, so they are ignored.
+	s.Init(file, src, func(token.Position, string) {}, scanner.ScanComments)
+
+	var b strings.Builder
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		if lit == "" {
+			lit = tok.String()
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(lit)
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
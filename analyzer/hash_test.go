@@ -0,0 +1,50 @@
+package analyzer
+
+import "testing"
+
+func TestContentHash(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"a.go": `package testpkg
+
+func Sum(xs []int) int {
+	total := 0
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
+`,
+		"b.go": `package testpkg
+
+func Sum(xs []int) int {
+        total := 0
+        for _, x := range xs { total += x }
+        return total
+}
+`,
+		"c.go": `package testpkg
+
+func Sum(xs []int) int {
+	total := 1
+	for _, x := range xs {
+		total += x
+	}
+	return total
+}
+`,
+	})
+
+	hashes := map[string]string{}
+	for _, node := range analyzer.Nodes {
+		if node.ContentHash == "" {
+			t.Errorf("Expected a content hash for %s", node.ID)
+		}
+		hashes[node.ID] = node.ContentHash
+	}
+	if hashes["a.Sum"] != hashes["b.Sum"] {
+		t.Error("Expected reformatting-only changes to keep the hash")
+	}
+	if hashes["a.Sum"] == hashes["c.Sum"] {
+		t.Error("Expected a changed body to change the hash")
+	}
+}
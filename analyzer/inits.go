@@ -0,0 +1,122 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// varInitName is the name of the synthetic per-file node that package-level
+// var and const initializer calls are attributed to.
+const varInitName = "<init>"
+
+// initFuncID returns the ID for the next init function in filePath. A file
+// may declare several, so they are numbered in order: file.init#1, ...
+func (a *GoAnalyzer) initFuncID(fn *ast.FuncDecl, filePath string) string {
+	a.initCounts[filePath]++
+	id := a.getComponentIDForFile(filePath, fmt.Sprintf("init#%d", a.initCounts[filePath]), "")
+	a.initIDs[fn] = id
+	return id
+}
+
+// isInitFunc reports whether fn is a package initializer.
+func isInitFunc(fn *ast.FuncDecl) bool {
+	return fn.Recv == nil && fn.Name.Name == "init"
+}
+
+// initializerValues returns the initializer expressions of the file's
+// package-level var and const declarations that contain a call.
+func initializerValues(file *ast.File) []ast.Expr {
+	var values []ast.Expr
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || (gen.Tok != token.VAR && gen.Tok != token.CONST) {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			for _, value := range spec.(*ast.ValueSpec).Values {
+				if containsCall(value) {
+					values = append(values, value)
+				}
+			}
+		}
+	}
+	return values
+}
+
+func containsCall(n ast.Node) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if _, ok := n.(*ast.CallExpr); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// visitVarInitializers emits the file's "<init>" node when package-level
+// initializers make calls. Its span runs from the first such initializer to
+// the last.
+func (a *GoAnalyzer) visitVarInitializers(file *ast.File, filePath string, source *fileSource) {
+	values := initializerValues(file)
+	if len(values) == 0 {
+		return
+	}
+
+	relativePath, _ := filepath.Rel(a.RepoAbs, filePath)
+	componentID := a.getComponentIDForFile(filePath, varInitName, "")
+	startPos := a.FileSet.Position(values[0].Pos())
+	endPos := a.FileSet.Position(values[len(values)-1].End())
+
+	var sourceCode, hash string
+	if src, ok := a.nodeSpan(source, filePath, componentID, startPos.Offset, endPos.Offset); ok {
+		hash = contentHash(src)
+		if a.IncludeSource {
+			sourceCode = string(src)
+		}
+	}
+
+	a.addNode(models.Node{
+		ID:            componentID,
+		Name:          varInitName,
+		ComponentType: "function",
+		FilePath:      filePath,
+		RelativePath:  relativePath,
+		StartLine:     startPos.Line,
+		EndLine:       endPos.Line,
+		StartCol:      startPos.Column,
+		EndCol:        endPos.Column,
+		StartByte:     startPos.Offset,
+		EndByte:       endPos.Offset,
+		NodeType:      "var_init",
+		ComponentID:   componentID,
+		DisplayName:   "var initializers in " + filepath.Base(filePath),
+		DependsOn:     []string{},
+		SourceCode:    sourceCode,
+		ContentHash:   hash,
+	})
+
+	if a.ExtractClosures {
+		roots := make([]ast.Node, len(values))
+		for i, value := range values {
+			roots[i] = value
+		}
+		a.collectClosures(componentID, filePath, source, roots...)
+	}
+}
+
+// collectVarInitializerCalls attributes calls in package-level initializers
+// to the file's "<init>" node.
+func (a *GoAnalyzer) collectVarInitializerCalls(filePath string, info *fileInfo) {
+	callerID := a.getComponentIDForFile(filePath, varInitName, "")
+	if !a.CollectedNodeIDs[callerID] {
+		return
+	}
+	for _, value := range initializerValues(info.file) {
+		a.walkCalls(value, callerID, "", "", filePath, info.info, info.pkg)
+	}
+}
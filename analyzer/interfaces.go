@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// interfaceMethods lists the full method set of the interface declared by
+// ts, including methods promoted from embedded interfaces, each with the
+// interface that declares it. It returns nil without type information.
+func (a *GoAnalyzer) interfaceMethods(ts *ast.TypeSpec, selfID string, typeInfo *types.Info) []models.MethodSig {
+	if typeInfo == nil {
+		return nil
+	}
+	obj, ok := typeInfo.Defs[ts.Name].(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+
+	qualifier := func(pkg *types.Package) string {
+		if pkg == obj.Pkg() {
+			return ""
+		}
+		return pkg.Name()
+	}
+	sigs := make([]models.MethodSig, 0, iface.NumMethods())
+	for i := 0; i < iface.NumMethods(); i++ {
+		method := iface.Method(i)
+		declaring := a.declaringInterface(iface, method, qualifier)
+		if declaring == "" {
+			declaring = selfID
+		}
+		sigs = append(sigs, models.MethodSig{
+			Name:               method.Name(),
+			Signature:          strings.TrimPrefix(types.TypeString(method.Type(), qualifier), "func"),
+			DeclaringInterface: declaring,
+		})
+	}
+	return sigs
+}
+
+// declaringInterface finds the named interface that explicitly declares
+// method, searching iface's own methods and then its embedded interfaces
+// depth-first. It returns "" when iface declares the method itself. Local
+// interfaces are named by component ID, others by qualified type name.
+func (a *GoAnalyzer) declaringInterface(iface *types.Interface, method *types.Func, qualifier types.Qualifier) string {
+	for i := 0; i < iface.NumExplicitMethods(); i++ {
+		if iface.ExplicitMethod(i) == method {
+			return ""
+		}
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		embedded := iface.EmbeddedType(i)
+		inner, ok := embedded.Underlying().(*types.Interface)
+		if !ok || !hasMethod(inner, method) {
+			continue
+		}
+		if found := a.declaringInterface(inner, method, qualifier); found != "" {
+			return found
+		}
+		named, ok := embedded.(*types.Named)
+		if !ok {
+			return "" // An inline interface literal; attribute it to iface.
+		}
+		if id := a.localNamedTypeID(named); id != "" {
+			return id
+		}
+		return types.TypeString(named, qualifier)
+	}
+	return ""
+}
+
+func hasMethod(iface *types.Interface, method *types.Func) bool {
+	for i := 0; i < iface.NumMethods(); i++ {
+		if iface.Method(i) == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+func TestInterfaceMethodOrigins(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"rw.go": `package testpkg
+
+import "io"
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type Writer interface {
+	Write(p []byte) (int, error)
+}
+
+type ReadWriteCloser interface {
+	Reader
+	Writer
+	io.Closer
+	Flush() error
+}
+`,
+	})
+
+	var rwc *models.Node
+	for i := range analyzer.Nodes {
+		if analyzer.Nodes[i].ID == "rw.ReadWriteCloser" {
+			rwc = &analyzer.Nodes[i]
+		}
+	}
+	if rwc == nil {
+		t.Fatal("Expected a node for rw.ReadWriteCloser")
+	}
+
+	origins := map[string]string{}
+	for _, m := range rwc.InterfaceMethods {
+		origins[m.Name] = m.DeclaringInterface
+		if m.Name == "Read" && m.Signature != "(p []byte) (int, error)" {
+			t.Errorf("Expected Read's signature, got %q", m.Signature)
+		}
+	}
+	want := map[string]string{
+		"Read":  "rw.Reader",
+		"Write": "rw.Writer",
+		"Close": "io.Closer",
+		"Flush": "rw.ReadWriteCloser",
+	}
+	for name, origin := range want {
+		if origins[name] != origin {
+			t.Errorf("Expected %s to come from %s, got %q", name, origin, origins[name])
+		}
+	}
+	if len(origins) != len(want) {
+		t.Errorf("Expected the full method set %v, got %v", want, origins)
+	}
+}
@@ -0,0 +1,19 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// localTypeID returns the synthetic ID of a type declared inside the body of
+// the function enclosingID: enclosingID$Name, or enclosingID$Name#2, ... when
+// the function declares several local types of that name in different
+// scopes.
+func (a *GoAnalyzer) localTypeID(ts *ast.TypeSpec, enclosingID string) string {
+	id := fmt.Sprintf("%s$%s", enclosingID, ts.Name.Name)
+	for n := 2; a.CollectedNodeIDs[id]; n++ {
+		id = fmt.Sprintf("%s$%s#%d", enclosingID, ts.Name.Name, n)
+	}
+	a.localTypeIDs[ts.Name.Pos()] = id
+	return id
+}
@@ -0,0 +1,33 @@
+package analyzer
+
+import (
+	"log/slog"
+	"path/filepath"
+)
+
+// discardLogger stands in for a nil Logger.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// log returns Logger, or a logger that drops every record when it is nil.
+func (a *GoAnalyzer) log() *slog.Logger {
+	if a.Logger != nil {
+		return a.Logger
+	}
+	return discardLogger
+}
+
+// logSkipped logs at debug level that filename was left out of the
+// analysis and why.
+func (a *GoAnalyzer) logSkipped(filename string, reason string) {
+	a.log().Debug("skipped file", "file", a.logPath(filename), "reason", reason)
+}
+
+// logPath is filename relative to the repo root, with slashes, as in node
+// relative paths.
+func (a *GoAnalyzer) logPath(filename string) string {
+	relativePath, err := filepath.Rel(a.RepoAbs, filename)
+	if err != nil {
+		return filename
+	}
+	return slashPath(relativePath)
+}
@@ -0,0 +1,38 @@
+package analyzer
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeLogger(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"a.go":   "package main\n\nfunc main() { helper() }\n",
+		"b.go":   "package main\n\nfunc helper() {}\n\ntype T struct{}\n",
+		"gen.go": "// Code generated by hand. DO NOT EDIT.\n\npackage main\n\nfunc generated() {}\n",
+	})
+
+	var buf bytes.Buffer
+	analyzer.Reset()
+	analyzer.ExcludeGenerated = true
+	analyzer.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	logged := buf.String()
+	for _, want := range []string{
+		`level=INFO msg="loaded packages" dir=. packages=1`,
+		`level=DEBUG msg="collected nodes" file=a.go nodes=1`,
+		`level=DEBUG msg="collected nodes" file=b.go nodes=2`,
+		`level=DEBUG msg="collected relationships" file=a.go relationships=1`,
+		`level=DEBUG msg="skipped file" file=gen.go reason=generated`,
+		`level=INFO msg="analysis finished" files=2 nodes=3 relationships=1`,
+	} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("expected a record containing %s, got:\n%s", want, logged)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package analyzer
+
+// ID styles for Options.IDStyle.
+const (
+	// IDStyleDottedPath builds IDs from the repo-relative file path with
+	// separators replaced by dots: analyzer.transform.NestMethods.
+	IDStyleDottedPath = "dotted-path"
+	// IDStyleSlashPath keeps the slashes of the file path:
+	// analyzer/transform.NestMethods.
+	IDStyleSlashPath = "slash-path"
+	// IDStyleImportPath uses the package's import path, so declarations in
+	// different files of a package share a prefix:
+	// example.com/foo/analyzer.NestMethods. Without a module there is no
+	// import path, and the package directory (plus any ModulePrefix) is
+	// used instead: analyzer.NestMethods.
+	IDStyleImportPath = "import-path"
+)
+
+// Options controls what the analyzer extracts and emits. It is embedded in
+// GoAnalyzer, so options can be set directly on an analyzer before Analyze.
+type Options struct {
+	// IncludeSource captures each declaration's source text in
+	// Node.SourceCode. Disable it to shrink the output when consumers can
+	// re-read spans from the files using the line information.
+	IncludeSource bool
+
+	// MaxSourceBytes, when positive, cuts Node.SourceCode longer than this
+	// many bytes at a rune boundary, appends sourceTruncatedMarker and sets
+	// Node.SourceTruncated. Lines, bytes and ContentHash still describe the
+	// full declaration.
+	MaxSourceBytes int
+
+	// ResolveInterfaceDispatch adds "dynamic_call" edges from a call through
+	// a repo-local interface to every repo-local type implementing it.
+	ResolveInterfaceDispatch bool
+
+	// SlashPaths writes Node.FilePath, Node.RelativePath and the Imports
+	// keys with forward slashes on every OS, so output from Windows and
+	// Unix can be diffed. Component IDs are separator-independent either way.
+	SlashPaths bool
+
+	// ExternalFieldTypes adds unresolved "has_field" edges to named types
+	// from outside the repo (e.g. sql.DB); by default only repo-local field
+	// types are linked.
+	ExternalFieldTypes bool
+
+	// PackageGraph emits a "package" node for each analyzed package, with
+	// its import path (or, without a module, its directory) as ID, and
+	// "imports" edges from it to the repo packages it imports.
+	PackageGraph bool
+
+	// ExternalImports adds unresolved "imports" edges to packages from
+	// outside the repo, such as the standard library, to PackageGraph.
+	ExternalImports bool
+
+	// CollectImports records each file's imports in GoAnalyzer.Imports so
+	// consumers can map aliases in external callee names to import paths.
+	CollectImports bool
+
+	// CollectPackageDocs records each package's doc comment in
+	// GoAnalyzer.PackageDocs, once per package.
+	CollectPackageDocs bool
+
+	// CollectGenerate records each file's //go:generate directives in
+	// GoAnalyzer.Generate.
+	CollectGenerate bool
+
+	// MarkStdlib sets IsStdlib on calls whose type-resolved callee is in the
+	// standard library, so consumers can style them apart from third-party
+	// and unresolved calls.
+	MarkStdlib bool
+
+	// ExtractClosures emits function literals as "closure" nodes with IDs
+	// like enclosingID$func1, and attributes calls made inside a literal to
+	// it rather than to the enclosing function.
+	ExtractClosures bool
+
+	// LineComments records the comment trailing a declaration on its last
+	// line (func F() {} // note) in Node.LineComment, apart from Docstring.
+	LineComments bool
+
+	// ExtractLocalTypes emits struct and interface types declared inside
+	// function bodies as nodes with IDs like enclosingID$Name and
+	// EnclosingFunc set. Without it, local types are skipped.
+	ExtractLocalTypes bool
+
+	// UseGitignore applies the repo's .gitignore rules: ignored directories
+	// are not searched for modules and ignored files emit no nodes or
+	// relationships.
+	UseGitignore bool
+
+	// IncludeVendor also analyzes vendored packages, as if they were part
+	// of the repo: those listed in each module's vendor/modules.txt, which
+	// is then loaded with -mod=vendor, or every package under vendor/ in a
+	// repo without modules.
+	IncludeVendor bool
+
+	// VendorPackages, when non-empty, limits IncludeVendor to these import
+	// paths and the packages below them.
+	VendorPackages []string
+
+	// LazySource reads each node's source from disk as the node is
+	// emitted, instead of reading every file up front and keeping it in
+	// memory during the analysis. It is implied when IncludeSource is off,
+	// where the source is only read to compute ContentHash.
+	LazySource bool
+
+	// Jobs limits how many module roots are loaded and type-checked at
+	// once. Zero means runtime.GOMAXPROCS(0).
+	Jobs int
+
+	// IncludeTests also analyzes _test.go files, including external test
+	// packages, and sets TestKind on the tests, benchmarks, fuzz tests and
+	// examples among their functions.
+	IncludeTests bool
+
+	// ExcludeGenerated skips files marked generated by a "// Code generated
+	// ... DO NOT EDIT." comment before the package clause (see
+	// ast.IsGenerated). They are still loaded, so calls into them resolve
+	// to their IDs, but emit no nodes or relationships.
+	ExcludeGenerated bool
+
+	// StdlibInterfaces lists standard library interfaces, by import path
+	// and name ("fmt.Stringer", "encoding/json.Marshaler", or "error"), to
+	// check repo-local types against; the ones a type implements are
+	// recorded in its node's SatisfiesStdlib. Needs type information. See
+	// DefaultStdlibInterfaces.
+	StdlibInterfaces []string
+
+	// RelationshipTypes, when non-empty, limits collection to these
+	// relationship types ("calls", "dynamic_call", "produces", "returns",
+	// "asserts", "converts", "has_field", "owns", "overrides", "imports");
+	// passes for the others are skipped. "calls" includes "recurses" edges.
+	RelationshipTypes []string
+
+	// FollowSymlinks descends into symlinked directories that point outside
+	// the repo, when finding modules and loading packages, as if their
+	// contents lived at the link. Links into the repo are not followed, as
+	// their files are already analyzed at their real location.
+	FollowSymlinks bool
+
+	// VCSInfo records the repo's git commit and module version in
+	// GitCommit and ModuleVersion; see loadVCSInfo.
+	VCSInfo bool
+
+	// IDStyle selects how component IDs are built; empty means
+	// IDStyleDottedPath.
+	IDStyle string
+
+	// ModulePrefix is prepended to every component ID, joined with the
+	// style's separator. Import-path IDs already start with the module
+	// path, so there it only applies to files outside any module.
+	ModulePrefix string
+
+	// ExportedOnly drops unexported declarations (and methods of unexported
+	// types) after collection; see FilterExported.
+	ExportedOnly bool
+
+	// DeadCodeExported makes DeadCodeCandidates also report exported
+	// functions and methods, which callers outside the repo may still use.
+	DeadCodeExported bool
+
+	// InternalAsPrivate treats packages under an internal/ directory as
+	// private API: their nodes get Visibility "internal" and are dropped by
+	// ExportedOnly, and relationships into them from other packages are
+	// flagged with IntoInternal.
+	InternalAsPrivate bool
+
+	// CacheDir, when set, stores each run's results there per module root
+	// and reuses those of a module, without loading it, while its go.mod,
+	// go.sum and .go files and those of the repo modules it depends on are
+	// unchanged. Other options, the go env build settings (GOOS, GOARCH,
+	// GOFLAGS, CGO_ENABLED and the Go version) and, with UseGitignore, the
+	// ignore files must match too. Runs with Patterns or Files are not
+	// cached.
+	CacheDir string
+
+	// Patterns, when non-empty, are package patterns (./..., ./foo, an
+	// import path) loaded from the repo root instead of discovering and
+	// loading every module in the repo.
+	Patterns []string
+
+	// Files, when non-empty, restricts nodes and relationships to these
+	// files (absolute, or relative to the repo root). Their whole packages
+	// are still loaded so calls between and out of them resolve; a file in
+	// no module is parsed without type information. Files cannot be
+	// combined with Patterns.
+	Files []string
+}
+
+// DefaultOptions returns the options NewGoAnalyzer starts with.
+func DefaultOptions() Options {
+	return Options{
+		IncludeSource: true,
+		SlashPaths:    true,
+		IDStyle:       IDStyleDottedPath,
+	}
+}
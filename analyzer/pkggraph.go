@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// packageKey identifies the package of a file: its import path, or its
+// slash-separated directory relative to the repo root without one.
+func (a *GoAnalyzer) packageKey(filePath string, info *fileInfo) string {
+	if info.pkgPath != "" {
+		return info.pkgPath
+	}
+	dir, _ := filepath.Rel(a.RepoAbs, filepath.Dir(filePath))
+	return slashPath(dir)
+}
+
+// collectPackageGraph emits a "package" node for each package among the
+// files, with packageKey as its ID, and an "imports" relationship from it
+// to each package its files import: resolved for packages in the repo and,
+// with ExternalImports, unresolved for the others. Each pair is linked
+// once. Import paths come from the type information when there is some;
+// without a module, packages are keyed by directory, so imports of repo
+// packages cannot be matched and count as external. The packages of
+// modules served from the cache get their cached node and edges.
+func (a *GoAnalyzer) collectPackageGraph(filenames []string, fileInfos map[string]*fileInfo) {
+	var keys []string
+	files := map[string][]string{}
+	for _, filename := range filenames {
+		key := a.packageKey(filename, fileInfos[filename])
+		if _, ok := files[key]; !ok {
+			keys = append(keys, key)
+		}
+		files[key] = append(files[key], filename)
+	}
+	cached := a.cachedPackages()
+	for key := range cached {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if cached[key] != nil {
+			a.addNode(cached[key].Node)
+			continue
+		}
+		first := fileInfos[files[key][0]]
+		dir := filepath.Dir(files[key][0])
+		relativePath, _ := filepath.Rel(a.RepoAbs, dir)
+		node := models.Node{
+			ID:            key,
+			Name:          first.pkgName,
+			ComponentType: "package",
+			FilePath:      dir,
+			RelativePath:  relativePath,
+			PackageName:   first.pkgName,
+			PackagePath:   first.pkgPath,
+			NodeType:      "package",
+			ComponentID:   key,
+			DisplayName:   "package " + first.pkgName,
+			DependsOn:     []string{},
+			Exported:      true,
+		}
+		for _, filename := range files[key] {
+			if doc := fileInfos[filename].file.Doc; doc != nil {
+				node.HasDocstring = true
+				node.Docstring = doc.Text()
+				break
+			}
+		}
+		a.addNode(node)
+	}
+
+	if !a.emits("imports") {
+		return
+	}
+	for _, key := range keys {
+		if cached[key] != nil {
+			a.Relationships = append(a.Relationships, cached[key].Imports...)
+			continue
+		}
+		seen := map[string]bool{}
+		for _, filename := range files[key] {
+			info := fileInfos[filename]
+			for _, spec := range info.file.Imports {
+				importPath, err := strconv.Unquote(spec.Path.Value)
+				if err != nil {
+					continue
+				}
+				if info.info != nil && info.info.PkgNameOf(spec) != nil {
+					importPath = info.info.PkgNameOf(spec).Imported().Path()
+				}
+				if importPath == "C" || seen[importPath] || (!a.CollectedNodeIDs[importPath] && !a.ExternalImports) {
+					continue
+				}
+				seen[importPath] = true
+				a.Relationships = append(a.Relationships, models.CallRelationship{
+					Caller:           key,
+					Callee:           importPath,
+					CallLine:         a.FileSet.Position(spec.Pos()).Line,
+					RelationshipType: "imports",
+					IsResolved:       a.CollectedNodeIDs[importPath],
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package analyzer
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestPackageGraph(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"a/a.go": `// Package a uses b.
+package a
+
+import (
+	"fmt"
+
+	"example.com/test/b"
+)
+
+func A() { fmt.Println(b.B()) }
+`,
+		"a/more.go": `package a
+
+import bee "example.com/test/b"
+
+func More() int { return bee.B() }
+`,
+		"b/b.go": "package b\n\nfunc B() int { return 1 }\n",
+	})
+	if analyzer.CollectedNodeIDs["example.com/test/a"] {
+		t.Fatal("expected no package nodes without PackageGraph")
+	}
+
+	imports := func() map[string]bool {
+		edges := map[string]bool{}
+		for _, rel := range analyzer.Relationships {
+			if rel.RelationshipType == "imports" {
+				key := rel.Caller + " -> " + rel.Callee
+				if _, dup := edges[key]; dup {
+					t.Errorf("expected %s once", key)
+				}
+				edges[key] = rel.IsResolved
+			}
+		}
+		return edges
+	}
+
+	analyzer.Reset()
+	analyzer.PackageGraph = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	for _, id := range []string{"example.com/test/a", "example.com/test/b"} {
+		i, ok := analyzer.nodeIndex[id]
+		if !ok || analyzer.Nodes[i].NodeType != "package" {
+			t.Fatalf("expected a package node %s", id)
+		}
+	}
+	if doc := analyzer.Nodes[analyzer.nodeIndex["example.com/test/a"]].Docstring; doc != "Package a uses b.\n" {
+		t.Errorf("expected the package doc, got %q", doc)
+	}
+	want := map[string]bool{"example.com/test/a -> example.com/test/b": true}
+	if got := imports(); !maps.Equal(got, want) {
+		t.Errorf("expected imports %v, got %v", want, got)
+	}
+
+	analyzer.Reset()
+	analyzer.ExternalImports = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	want["example.com/test/a -> fmt"] = false
+	if got := imports(); !maps.Equal(got, want) {
+		t.Errorf("expected imports %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,453 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// relationshipTypes lists the values accepted in Options.RelationshipTypes.
+// "calls" also covers "recurses", the type given to a function's calls to
+// itself.
+var relationshipTypes = []string{"calls", "dynamic_call", "produces", "returns", "asserts", "converts", "has_field", "owns", "overrides", "imports"}
+
+// emits reports whether relationships of relType should be collected.
+func (a *GoAnalyzer) emits(relType string) bool {
+	return len(a.RelationshipTypes) == 0 || slices.Contains(a.RelationshipTypes, relType)
+}
+
+// collectProduces emits a "produces" relationship from a function to each
+// repo-local type it returns a newly constructed instance of, either through
+// a composite literal (return &T{...}) or a constructor-style call (return
+// NewT(), newT() or new(T)). Other calls returning a repo-local type, such
+// as getters, are not construction and emit nothing. Return statements
+// inside function literals belong to the literal and are ignored.
+func (a *GoAnalyzer) collectProduces(callerID string, body *ast.BlockStmt, filePath string, typeInfo *types.Info) {
+	if !a.emits("produces") {
+		return
+	}
+	seen := map[string]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			for _, result := range x.Results {
+				typeID := a.producedTypeID(result, filePath, typeInfo)
+				if typeID == "" || seen[typeID] {
+					continue
+				}
+				seen[typeID] = true
+				a.Relationships = append(a.Relationships, models.CallRelationship{
+					Caller:           callerID,
+					Callee:           typeID,
+					CallLine:         a.FileSet.Position(result.Pos()).Line,
+					RelationshipType: "produces",
+					IsResolved:       a.CollectedNodeIDs[typeID],
+				})
+			}
+		}
+		return true
+	})
+}
+
+// producedTypeID returns the component ID of the repo-local type constructed
+// by a returned expression, or "" if the expression does not construct one.
+func (a *GoAnalyzer) producedTypeID(expr ast.Expr, filePath string, typeInfo *types.Info) string {
+	expr = ast.Unparen(expr)
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = ast.Unparen(unary.X)
+	}
+
+	switch x := expr.(type) {
+	case *ast.CompositeLit:
+		if typeInfo == nil {
+			// Without types, only a bare identifier can be mapped to a
+			// same-package type.
+			if ident, ok := x.Type.(*ast.Ident); ok && !isBuiltin(ident.Name) {
+				return a.getComponentIDForFile(filePath, ident.Name, "")
+			}
+			return ""
+		}
+	case *ast.CallExpr:
+		if typeInfo == nil {
+			return ""
+		}
+		if tv, ok := typeInfo.Types[x.Fun]; ok && tv.IsType() {
+			return "" // Conversion, not construction.
+		}
+		if !isConstructorCall(x) {
+			return ""
+		}
+	default:
+		return ""
+	}
+
+	return a.localNamedTypeID(typeInfo.TypeOf(expr))
+}
+
+// isConstructorCall reports whether call is to a function named like a
+// constructor: New..., new... or the new builtin.
+func isConstructorCall(call *ast.CallExpr) bool {
+	fun := ast.Unparen(call.Fun)
+	switch x := fun.(type) { // Generic instantiation, NewT[int]().
+	case *ast.IndexExpr:
+		fun = x.X
+	case *ast.IndexListExpr:
+		fun = x.X
+	}
+	var name string
+	switch x := fun.(type) {
+	case *ast.Ident:
+		name = x.Name
+	case *ast.SelectorExpr:
+		name = x.Sel.Name
+	}
+	return strings.HasPrefix(name, "New") || strings.HasPrefix(name, "new")
+}
+
+// collectConversion reports whether call is a type conversion such as
+// Celsius(f) rather than a function call, and emits a "converts"
+// relationship from the caller to the target type when it is a repo-local
+// named type. Conversions to other types emit nothing.
+func (a *GoAnalyzer) collectConversion(callerID string, call *ast.CallExpr, typeInfo *types.Info) bool {
+	tv, ok := typeInfo.Types[call.Fun]
+	if !ok || !tv.IsType() {
+		return false
+	}
+	typeID := a.localNamedTypeID(tv.Type)
+	if typeID == "" || !a.emits("converts") {
+		return true
+	}
+	a.Relationships = append(a.Relationships, models.CallRelationship{
+		Caller:           callerID,
+		Callee:           typeID,
+		CallLine:         a.FileSet.Position(call.Pos()).Line,
+		RelationshipType: "converts",
+		IsResolved:       a.CollectedNodeIDs[typeID],
+	})
+	return true
+}
+
+// collectReturns emits a "returns" relationship from a function to each
+// repo-local named type among its results.
+func (a *GoAnalyzer) collectReturns(callerID string, fn *ast.FuncDecl, typeInfo *types.Info) {
+	if typeInfo == nil || fn.Type.Results == nil || !a.emits("returns") {
+		return
+	}
+	seen := map[string]bool{}
+	for _, field := range fn.Type.Results.List {
+		typeID := a.localNamedTypeID(typeInfo.TypeOf(field.Type))
+		if typeID == "" || seen[typeID] {
+			continue
+		}
+		seen[typeID] = true
+		a.Relationships = append(a.Relationships, models.CallRelationship{
+			Caller:           callerID,
+			Callee:           typeID,
+			CallLine:         a.FileSet.Position(field.Pos()).Line,
+			RelationshipType: "returns",
+			IsResolved:       a.CollectedNodeIDs[typeID],
+		})
+	}
+}
+
+// collectAsserts emits an "asserts" relationship from a function to each
+// repo-local type it asserts an interface value to, through v.(*T) or a
+// "case *T:" clause of a type switch. Comma-ok assertions and "case nil"
+// are skipped, as are assertions inside function literals.
+func (a *GoAnalyzer) collectAsserts(callerID string, body *ast.BlockStmt, typeInfo *types.Info) {
+	if typeInfo == nil || !a.emits("asserts") {
+		return
+	}
+	seen := map[string]bool{}
+	emit := func(typeExpr ast.Expr) {
+		if tv, ok := typeInfo.Types[typeExpr]; !ok || tv.IsNil() {
+			return
+		}
+		typeID := a.localNamedTypeID(typeInfo.TypeOf(typeExpr))
+		if typeID == "" || seen[typeID] {
+			return
+		}
+		seen[typeID] = true
+		a.Relationships = append(a.Relationships, models.CallRelationship{
+			Caller:           callerID,
+			Callee:           typeID,
+			CallLine:         a.FileSet.Position(typeExpr.Pos()).Line,
+			RelationshipType: "asserts",
+			IsResolved:       a.CollectedNodeIDs[typeID],
+		})
+	}
+
+	commaOK := map[*ast.TypeAssertExpr]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			if len(x.Lhs) == 2 && len(x.Rhs) == 1 {
+				if assert, ok := ast.Unparen(x.Rhs[0]).(*ast.TypeAssertExpr); ok {
+					commaOK[assert] = true
+				}
+			}
+		case *ast.ValueSpec:
+			if len(x.Names) == 2 && len(x.Values) == 1 {
+				if assert, ok := ast.Unparen(x.Values[0]).(*ast.TypeAssertExpr); ok {
+					commaOK[assert] = true
+				}
+			}
+		case *ast.TypeAssertExpr:
+			// x.(type) in a type switch header has no Type.
+			if x.Type != nil && !commaOK[x] {
+				emit(x.Type)
+			}
+		case *ast.TypeSwitchStmt:
+			for _, stmt := range x.Body.List {
+				for _, expr := range stmt.(*ast.CaseClause).List {
+					emit(expr)
+				}
+			}
+		}
+		return true
+	})
+}
+
+// ownerTypeID returns the component ID of the type declaring method fn,
+// looked up through the type information so that a receiver declared in
+// another file still maps to its type's node. It returns "" without types.
+func (a *GoAnalyzer) ownerTypeID(fn *ast.FuncDecl, typeInfo *types.Info) string {
+	if typeInfo == nil {
+		return ""
+	}
+	obj, ok := typeInfo.Defs[fn.Name].(*types.Func)
+	if !ok {
+		return ""
+	}
+	recv := obj.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return ""
+	}
+	return a.localNamedTypeID(recv.Type())
+}
+
+// linkOwners emits an "owns" relationship from each type node to its
+// method nodes. It runs after the node pass so every type node is known;
+// OwnerTypeID is cleared when the owning type has no node (e.g. a named
+// func or basic type).
+func (a *GoAnalyzer) linkOwners() {
+	for i := range a.Nodes {
+		method := &a.Nodes[i]
+		if method.OwnerTypeID == "" {
+			continue
+		}
+		if !a.CollectedNodeIDs[method.OwnerTypeID] {
+			method.OwnerTypeID = ""
+			continue
+		}
+		if !a.emits("owns") {
+			continue
+		}
+		a.Relationships = append(a.Relationships, models.CallRelationship{
+			Caller:           method.OwnerTypeID,
+			Callee:           method.ID,
+			CallLine:         method.StartLine,
+			RelationshipType: "owns",
+			IsResolved:       true,
+		})
+	}
+}
+
+// linkOverrides emits an "overrides" relationship from each method of a
+// repo-local struct type to the method of the same name it shadows in an
+// embedded type, one per embedded field that promotes such a method. The
+// shadowed method is named by ID in the repo and by qualified name (e.g.
+// sync.Mutex.Lock) outside it; interface methods have no nodes, so edges
+// to them stay unresolved. Needs type information.
+func (a *GoAnalyzer) linkOverrides() {
+	if !a.emits("overrides") {
+		return
+	}
+	for _, typeName := range a.repoTypeNames() {
+		named, ok := typeName.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := range named.NumMethods() {
+			method := named.Method(i)
+			methodID := a.getComponentIDForPos(method.Pos(), method.Name(), typeName.Name())
+			if !a.CollectedNodeIDs[methodID] {
+				continue
+			}
+			for j := range st.NumFields() {
+				field := st.Field(j)
+				if !field.Embedded() {
+					continue
+				}
+				obj, _, _ := types.LookupFieldOrMethod(field.Type(), true, method.Pkg(), method.Name())
+				shadowed, ok := obj.(*types.Func)
+				if !ok {
+					continue
+				}
+				shadowedID := a.methodName(shadowed, typeName.Pkg())
+				a.Relationships = append(a.Relationships, models.CallRelationship{
+					Caller:           methodID,
+					Callee:           shadowedID,
+					CallLine:         a.FileSet.Position(method.Pos()).Line,
+					RelationshipType: "overrides",
+					IsResolved:       a.CollectedNodeIDs[shadowedID],
+				})
+			}
+		}
+	}
+}
+
+// methodName returns the ID of method fn when it is declared in the repo,
+// and otherwise its name qualified by its receiver type, e.g.
+// bytes.Buffer.Write, leaving out the package name for pkg.
+func (a *GoAnalyzer) methodName(fn *types.Func, pkg *types.Package) string {
+	if a.isPosInRepo(fn.Pos()) {
+		return a.getComponentIDForPos(fn.Pos(), fn.Name(), receiverTypeString(fn.Type()))
+	}
+	recv := fn.Type().(*types.Signature).Recv().Type()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	return types.TypeString(recv, func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	}) + "." + fn.Name()
+}
+
+// collectFields emits a "has_field" relationship from the struct declared
+// by ts to each repo-local named type used by its named fields, looking
+// through pointers, slices, arrays, maps and channels, and lists the types
+// in the struct node's DependsOn. Each type is linked once. With
+// ExternalFieldTypes, named types from outside the repo are linked as
+// unresolved edges to their qualified name (e.g. sql.DB). Embedded fields
+// are not included.
+//
+// Only the type written in each field is looked at, never the fields of
+// the types it names, so self-referential and mutually referential types
+// need no cycle check.
+func (a *GoAnalyzer) collectFields(ts *ast.TypeSpec, filePath string, typeInfo *types.Info) {
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok || !a.emits("has_field") {
+		return
+	}
+	structID := a.getComponentIDForFile(filePath, ts.Name.Name, "")
+	if typeInfo != nil {
+		if obj, ok := typeInfo.Defs[ts.Name].(*types.TypeName); ok {
+			structID = a.localNamedTypeID(obj.Type())
+		}
+	}
+	if !a.CollectedNodeIDs[structID] {
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			continue
+		}
+		typeID := a.fieldTypeID(field.Type, filePath, typeInfo)
+		if typeID == "" || seen[typeID] {
+			continue
+		}
+		seen[typeID] = true
+		a.Relationships = append(a.Relationships, models.CallRelationship{
+			Caller:           structID,
+			Callee:           typeID,
+			CallLine:         a.FileSet.Position(field.Pos()).Line,
+			RelationshipType: "has_field",
+			IsResolved:       a.CollectedNodeIDs[typeID],
+		})
+		if i, ok := a.nodeIndex[structID]; ok && a.CollectedNodeIDs[typeID] {
+			a.Nodes[i].DependsOn = append(a.Nodes[i].DependsOn, typeID)
+		}
+	}
+}
+
+// fieldTypeID returns the ID of the named type behind a field type: the
+// component ID for a repo-local type, the qualified name for an external
+// one when ExternalFieldTypes is set, and "" otherwise.
+func (a *GoAnalyzer) fieldTypeID(expr ast.Expr, filePath string, typeInfo *types.Info) string {
+	if typeInfo == nil {
+		// Without types, only a bare identifier behind the containers can
+		// be mapped to a same-package type.
+		for {
+			switch x := expr.(type) {
+			case *ast.StarExpr:
+				expr = x.X
+				continue
+			case *ast.ArrayType:
+				expr = x.Elt
+				continue
+			case *ast.MapType:
+				expr = x.Value
+				continue
+			case *ast.ChanType:
+				expr = x.Value
+				continue
+			case *ast.Ident:
+				if !isBuiltin(x.Name) {
+					return a.getComponentIDForFile(filePath, x.Name, "")
+				}
+			}
+			return ""
+		}
+	}
+
+	t := typeInfo.TypeOf(expr)
+	if typeID := a.localNamedTypeID(t); typeID != "" {
+		return typeID
+	}
+	named := elemNamed(t)
+	if !a.ExternalFieldTypes || named == nil || named.Obj().Pkg() == nil {
+		return ""
+	}
+	return named.Obj().Pkg().Name() + "." + named.Obj().Name()
+}
+
+// elemNamed returns the named type behind t, looking through aliases,
+// pointers, slices, arrays, channels and map values, or nil if there is
+// none.
+func elemNamed(t types.Type) *types.Named {
+	// Pointer, Slice, Array, Chan and Map all expose their element via Elem.
+	for {
+		container, ok := types.Unalias(t).(interface{ Elem() types.Type })
+		if !ok {
+			break
+		}
+		t = container.Elem()
+	}
+	named, _ := types.Unalias(t).(*types.Named)
+	return named
+}
+
+// localNamedTypeID returns the component ID of the named type behind t when
+// that type is declared inside the repo. Pointers, slices, arrays, channels
+// and map values are looked through to their element type.
+func (a *GoAnalyzer) localNamedTypeID(t types.Type) string {
+	named := elemNamed(t)
+	if named == nil {
+		return ""
+	}
+	obj := named.Obj()
+	if id, ok := a.localTypeIDs[obj.Pos()]; ok {
+		return id
+	}
+	if !a.isPosInRepo(obj.Pos()) {
+		return ""
+	}
+	return a.getComponentIDForPos(obj.Pos(), obj.Name(), "")
+}
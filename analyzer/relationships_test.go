@@ -0,0 +1,465 @@
+package analyzer
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProducesRelationships(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"widget.go": `package testpkg
+
+type Widget struct{}
+
+func NewWidget() *Widget {
+	return &Widget{}
+}
+
+func Build(fancy bool) *Widget {
+	if fancy {
+		return NewWidget()
+	}
+	return &Widget{}
+}
+
+func Name() string {
+	return "widget"
+}
+
+var current = &Widget{}
+
+func Current() *Widget {
+	return current
+}
+
+func Cached() *Widget {
+	return Current()
+}
+
+func Fresh() *Widget {
+	return new(Widget)
+}
+`,
+	})
+
+	produces := map[string][]string{}
+	for _, rel := range analyzer.Relationships {
+		if rel.RelationshipType != "produces" {
+			continue
+		}
+		produces[rel.Caller] = append(produces[rel.Caller], rel.Callee)
+		if !rel.IsResolved {
+			t.Errorf("Expected produces edge %s -> %s to be resolved", rel.Caller, rel.Callee)
+		}
+	}
+
+	for _, caller := range []string{"widget.NewWidget", "widget.Build", "widget.Fresh"} {
+		got := produces[caller]
+		if len(got) != 1 || got[0] != "widget.Widget" {
+			t.Errorf("Expected %s to produce exactly [widget.Widget], got %v", caller, got)
+		}
+	}
+	for _, caller := range []string{"widget.Name", "widget.Current", "widget.Cached"} {
+		if got := produces[caller]; len(got) != 0 {
+			t.Errorf("Expected no produces edges from %s, got %v", caller, got)
+		}
+	}
+}
+
+func TestReturnsRelationships(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"foo.go": `package testpkg
+
+type Foo struct{}
+
+type Bar struct{}
+
+func NewFoo() *Foo { return &Foo{} }
+
+func List() ([]Foo, map[string]*Bar, error) { return nil, nil, nil }
+
+func Count() int { return 0 }
+`,
+	})
+
+	returns := map[string][]string{}
+	for _, rel := range analyzer.Relationships {
+		if rel.RelationshipType != "returns" {
+			continue
+		}
+		if !rel.IsResolved {
+			t.Errorf("Expected returns edge %s -> %s to be resolved", rel.Caller, rel.Callee)
+		}
+		returns[rel.Caller] = append(returns[rel.Caller], rel.Callee)
+	}
+
+	if got := returns["foo.NewFoo"]; len(got) != 1 || got[0] != "foo.Foo" {
+		t.Errorf("Expected NewFoo to return [foo.Foo], got %v", got)
+	}
+	if got := returns["foo.List"]; len(got) != 2 || got[0] != "foo.Foo" || got[1] != "foo.Bar" {
+		t.Errorf("Expected List to return [foo.Foo foo.Bar], got %v", got)
+	}
+	if got := returns["foo.Count"]; len(got) != 0 {
+		t.Errorf("Expected no returns edges from Count, got %v", got)
+	}
+}
+
+func TestAssertsRelationships(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"shapes.go": `package testpkg
+
+type Shape interface{ Area() float64 }
+
+type Circle struct{}
+
+func (Circle) Area() float64 { return 3 }
+
+type Square struct{}
+
+func (*Square) Area() float64 { return 1 }
+
+type Label struct{}
+
+func Describe(s Shape) string {
+	switch s.(type) {
+	case Circle:
+		return "circle"
+	case *Square:
+		return "square"
+	case nil:
+		return "nothing"
+	}
+	return ""
+}
+
+func MustCircle(s Shape) Circle {
+	return s.(Circle)
+}
+
+func MaybeLabel(v any) bool {
+	_, ok := v.(Label)
+	return ok
+}
+`,
+	})
+
+	asserts := map[string][]string{}
+	for _, rel := range analyzer.Relationships {
+		if rel.RelationshipType != "asserts" {
+			continue
+		}
+		asserts[rel.Caller] = append(asserts[rel.Caller], rel.Callee)
+		if !rel.IsResolved {
+			t.Errorf("Expected asserts edge %s -> %s to be resolved", rel.Caller, rel.Callee)
+		}
+	}
+
+	if got := asserts["shapes.Describe"]; len(got) != 2 || got[0] != "shapes.Circle" || got[1] != "shapes.Square" {
+		t.Errorf("Expected shapes.Describe to assert [shapes.Circle shapes.Square], got %v", got)
+	}
+	if got := asserts["shapes.MustCircle"]; len(got) != 1 || got[0] != "shapes.Circle" {
+		t.Errorf("Expected shapes.MustCircle to assert [shapes.Circle], got %v", got)
+	}
+	if got := asserts["shapes.MaybeLabel"]; len(got) != 0 {
+		t.Errorf("Expected comma-ok assertions to be skipped, got %v", got)
+	}
+}
+
+func TestConvertsRelationships(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"temp.go": `package testpkg
+
+import "time"
+
+type Celsius float64
+
+type Reading struct{ Value float64 }
+
+type Sample struct{ Value float64 }
+
+func Parse(f float64) Celsius {
+	return Celsius(f)
+}
+
+func Record(s Sample) Reading {
+	return (Reading)(s)
+}
+
+func Elapsed(n int) time.Duration {
+	return time.Duration(n) + time.Duration(len([]byte("x")))
+}
+`,
+	})
+
+	converts := map[string][]string{}
+	for _, rel := range analyzer.Relationships {
+		switch rel.RelationshipType {
+		case "converts":
+			converts[rel.Caller] = append(converts[rel.Caller], rel.Callee)
+			// Only struct and interface types have nodes to resolve to.
+			if rel.IsResolved != (rel.Callee == "temp.Reading") {
+				t.Errorf("Unexpected is_resolved %v for %s -> %s", rel.IsResolved, rel.Caller, rel.Callee)
+			}
+		case "calls":
+			if rel.Callee != "len" {
+				t.Errorf("Expected no calls edge for a conversion, got %s -> %s", rel.Caller, rel.Callee)
+			}
+		}
+	}
+
+	if got := converts["temp.Parse"]; len(got) != 1 || got[0] != "temp.Celsius" {
+		t.Errorf("Expected temp.Parse to convert to [temp.Celsius], got %v", got)
+	}
+	if got := converts["temp.Record"]; len(got) != 1 || got[0] != "temp.Reading" {
+		t.Errorf("Expected temp.Record to convert to [temp.Reading], got %v", got)
+	}
+	if got := converts["temp.Elapsed"]; len(got) != 0 {
+		t.Errorf("Expected conversions to non-local types to be skipped, got %v", got)
+	}
+}
+
+func TestOwnsRelationships(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"store.go": `package testpkg
+
+type Store struct{}
+
+func (s *Store) Get() {}
+`,
+		"store_put.go": `package testpkg
+
+func (s *Store) Put() {}
+
+type Count int
+
+func (c Count) Inc() Count { return c + 1 }
+`,
+	})
+
+	owners := map[string]string{}
+	for _, node := range analyzer.Nodes {
+		if node.ComponentType == "method" {
+			owners[node.ID] = node.OwnerTypeID
+		}
+	}
+	for _, method := range []string{"store.Store.Get", "store_put.Store.Put"} {
+		if owners[method] != "store.Store" {
+			t.Errorf("Expected %s to be owned by store.Store, got %q", method, owners[method])
+		}
+	}
+	if owner := owners["store_put.Count.Inc"]; owner != "" {
+		t.Errorf("Expected no owner for a method on a type without a node, got %q", owner)
+	}
+
+	owns := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		if rel.RelationshipType == "owns" {
+			owns[rel.Caller+" -> "+rel.Callee] = rel.IsResolved
+		}
+	}
+	for _, want := range []string{"store.Store -> store.Store.Get", "store.Store -> store_put.Store.Put"} {
+		if !owns[want] {
+			t.Errorf("Expected resolved owns edge %s, got %v", want, owns)
+		}
+	}
+	if len(owns) != 2 {
+		t.Errorf("Expected exactly 2 owns edges, got %v", owns)
+	}
+}
+
+func TestHasFieldRelationships(t *testing.T) {
+	files := map[string]string{
+		"order.go": `package testpkg
+
+import "strings"
+
+type Customer struct{}
+
+type Item struct{}
+
+type Order struct {
+	Buyer   *Customer
+	Seller  *Customer
+	Items   []Item
+	ByName  map[string]*Item
+	Note    string
+	builder strings.Builder
+}
+`,
+	}
+	analyzer := analyzeFiles(t, files)
+
+	fields := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		if rel.RelationshipType != "has_field" {
+			continue
+		}
+		key := rel.Caller + " -> " + rel.Callee
+		if fields[key] {
+			t.Errorf("Expected %s once", key)
+		}
+		fields[key] = rel.IsResolved
+	}
+	for _, want := range []string{"order.Order -> order.Customer", "order.Order -> order.Item"} {
+		if !fields[want] {
+			t.Errorf("Expected resolved has_field edge %s, got %v", want, fields)
+		}
+	}
+	if len(fields) != 2 {
+		t.Errorf("Expected only repo-local field types by default, got %v", fields)
+	}
+
+	analyzer, err := NewGoAnalyzer(filepath.Dir(analyzer.Nodes[0].FilePath))
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.ExternalFieldTypes = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	external := false
+	for _, rel := range analyzer.Relationships {
+		if rel.RelationshipType == "has_field" && rel.Callee == "strings.Builder" {
+			external = !rel.IsResolved
+		}
+	}
+	if !external {
+		t.Errorf("Expected an unresolved has_field edge to strings.Builder, got %+v", analyzer.Relationships)
+	}
+}
+
+func TestDependsOnSelfReferentialTypes(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"list.go": `package testpkg
+
+type List struct {
+	Value int
+	Next  *List
+	Prev  *List
+	Tree  *Tree
+}
+
+type Tree struct {
+	Children []*Tree
+	Owner    *List
+}
+`,
+	})
+
+	want := map[string][]string{
+		"list.List": {"list.List", "list.Tree"},
+		"list.Tree": {"list.Tree", "list.List"},
+	}
+	for _, node := range analyzer.Nodes {
+		if expected, ok := want[node.ID]; ok && !slices.Equal(node.DependsOn, expected) {
+			t.Errorf("%s: expected depends_on %v, got %v", node.ID, expected, node.DependsOn)
+		}
+	}
+}
+
+func TestRelationshipTypes(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeGoMod(t, tmpDir)
+	content := `package testpkg
+
+type Shape interface{ Area() int }
+
+type Square struct{ Next *Square }
+
+func (s Square) Area() int { return 0 }
+
+func NewSquare() *Square { return &Square{} }
+
+func Total(s Shape) int {
+	NewSquare()
+	return s.Area()
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "shape.go"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	analyzer, err := NewGoAnalyzer(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.ResolveInterfaceDispatch = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	all := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		all[rel.RelationshipType] = true
+	}
+	for _, relType := range []string{"calls", "dynamic_call", "produces", "has_field", "owns"} {
+		if !all[relType] {
+			t.Fatalf("Expected %s edges by default, got %v", relType, all)
+		}
+	}
+
+	analyzer.Reset()
+	analyzer.RelationshipTypes = []string{"calls"}
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(analyzer.Relationships) == 0 {
+		t.Fatal("Expected calls edges")
+	}
+	for _, rel := range analyzer.Relationships {
+		if rel.RelationshipType != "calls" {
+			t.Errorf("Expected only calls edges, got %+v", rel)
+		}
+	}
+
+	analyzer.Reset()
+	analyzer.RelationshipTypes = []string{"implements"}
+	if err := analyzer.Analyze(); err == nil || !strings.Contains(err.Error(), `unknown relationship type "implements"`) {
+		t.Errorf("Expected an unknown relationship type error, got %v", err)
+	}
+}
+
+func TestOverridesRelationships(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"animal.go": `package testpkg
+
+import "sync"
+
+type Named interface{ Name() string }
+
+type Base struct{}
+
+func (b *Base) Name() string { return "base" }
+
+func (b Base) Kind() string { return "base" }
+
+type Dog struct {
+	*Base
+	Named
+	sync.Mutex
+}
+
+func (d Dog) Name() string { return "dog" }
+
+func (d *Dog) Lock() {}
+
+func (d Dog) Bark() {}
+`})
+
+	got := map[string]bool{}
+	for _, rel := range analyzer.Relationships {
+		if rel.RelationshipType == "overrides" {
+			got[rel.Caller+" -> "+rel.Callee] = rel.IsResolved
+		}
+	}
+	want := map[string]bool{
+		"animal.Dog.Name -> animal.Base.Name":  true,
+		"animal.Dog.Name -> animal.Named.Name": false, // Interface methods have no nodes
+		"animal.Dog.Lock -> sync.Mutex.Lock":   false,
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("expected overrides edges %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"os"
+)
+
+// fileSource gives the node pass the bytes of a file's declarations: from
+// the whole file held in memory, or, with lazy set, read span by span from
+// disk so no file stays resident between the passes.
+type fileSource struct {
+	data []byte // The whole file, unless lazy
+
+	lazy bool
+	path string
+	file *os.File // Opened on the first read
+	err  error    // First open or read error
+}
+
+// newFileSource returns the source of the file at path whose content, if
+// already read, is content. See Options.LazySource.
+func (a *GoAnalyzer) newFileSource(path string, content []byte) (*fileSource, error) {
+	if a.LazySource || !a.IncludeSource {
+		return &fileSource{lazy: true, path: path}, nil
+	}
+	if content == nil {
+		var err error
+		if content, err = os.ReadFile(path); err != nil {
+			return nil, &ReadError{Path: path, Err: err}
+		}
+	}
+	return &fileSource{data: content}, nil
+}
+
+// span returns the bytes from offset start to end, or false if they are
+// not in the file or cannot be read.
+func (s *fileSource) span(start, end int) ([]byte, bool) {
+	if start < 0 || start > end {
+		return nil, false
+	}
+	if !s.lazy {
+		if end > len(s.data) {
+			return nil, false
+		}
+		return s.data[start:end], true
+	}
+	if s.err != nil {
+		return nil, false
+	}
+	if s.file == nil {
+		if s.file, s.err = os.Open(s.path); s.err != nil {
+			return nil, false
+		}
+	}
+	buf := make([]byte, end-start)
+	if _, err := s.file.ReadAt(buf, int64(start)); err != nil {
+		if err == io.EOF {
+			// The file shrank since it was parsed.
+			return nil, false
+		}
+		s.err = err
+		return nil, false
+	}
+	return buf, true
+}
+
+// nodeSpan returns the source of node id from offset start to end. Offsets
+// that do not fit the file, e.g. positions remapped by cgo or a file that
+// shrank after parsing, are recorded as a diagnostic and give no source
+// instead of a panic. Read errors are left for collectNodes to report once.
+func (a *GoAnalyzer) nodeSpan(source *fileSource, filePath string, id string, start, end int) ([]byte, bool) {
+	src, ok := source.span(start, end)
+	if !ok && source.err == nil {
+		a.Diagnostics = append(a.Diagnostics, fmt.Sprintf("byte span %d-%d of %s does not fit %s; source_code left empty", start, end, id, a.logPath(filePath)))
+	}
+	return src, ok
+}
+
+// docStart returns the offset at which a declaration starting at start
+// begins when its source includes doc. A doc comment positioned in another
+// file or after the declaration cannot be part of the span, so start is
+// kept then.
+func (a *GoAnalyzer) docStart(doc *ast.CommentGroup, start token.Position) int {
+	if doc == nil {
+		return start.Offset
+	}
+	docPos := a.FileSet.Position(doc.Pos())
+	if docPos.Filename != start.Filename || docPos.Offset > start.Offset {
+		return start.Offset
+	}
+	return docPos.Offset
+}
+
+// close releases the file opened by lazy reads and returns the first error
+// they ran into.
+func (s *fileSource) close() error {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	return s.err
+}
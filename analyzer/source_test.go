@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"go/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLazySource(t *testing.T) {
+	files := map[string]string{
+		"shapes/shape.go": `package shapes
+
+// Shape has an area.
+type Shape interface{ Area() float64 }
+
+// Square is a Shape.
+type Square struct{ Side float64 }
+
+func (s Square) Area() float64 { return s.Side * s.Side }
+`,
+		"shapes/total.go": `package shapes
+
+var unit = NewSquare(1)
+
+func NewSquare(side float64) Square { return Square{Side: side} }
+
+// Total sums the areas.
+func Total(shapes []Shape) float64 {
+	sum := 0.0
+	for _, s := range shapes {
+		sum += s.Area()
+	}
+	return sum
+}
+`,
+		"main.go": "package main\n\nfunc main() { println(\"ünïcode\") }\n",
+	}
+	analyzer := analyzeFiles(t, files)
+	want := map[string][2]string{} // ID -> source, hash
+	for _, node := range analyzer.Nodes {
+		want[node.ID] = [2]string{node.SourceCode, node.ContentHash}
+	}
+
+	analyzer.Reset()
+	analyzer.LazySource = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(analyzer.Nodes) != len(want) {
+		t.Fatalf("expected %d nodes, got %d", len(want), len(analyzer.Nodes))
+	}
+	for _, node := range analyzer.Nodes {
+		if got := [2]string{node.SourceCode, node.ContentHash}; got != want[node.ID] || got[0] == "" {
+			t.Errorf("%s: expected source and hash %q, got %q", node.ID, want[node.ID], got)
+		}
+	}
+
+	// Without source, files are read lazily just for the hashes.
+	analyzer.Reset()
+	analyzer.LazySource = false
+	analyzer.IncludeSource = false
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	for _, node := range analyzer.Nodes {
+		if node.SourceCode != "" || node.ContentHash != want[node.ID][1] {
+			t.Errorf("%s: expected no source and hash %s, got %q and %s", node.ID, want[node.ID][1], node.SourceCode, node.ContentHash)
+		}
+	}
+}
+
+func TestFileSourceSpan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.go")
+	if err := os.WriteFile(path, []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range []*fileSource{{data: []byte("package a\n")}, {lazy: true, path: path}} {
+		if got, ok := source.span(8, 9); !ok || string(got) != "a" {
+			t.Errorf("lazy=%v: expected \"a\", got %q, %v", source.lazy, got, ok)
+		}
+		for _, span := range [][2]int{{-1, 2}, {5, 4}, {8, 20}} {
+			if got, ok := source.span(span[0], span[1]); ok {
+				t.Errorf("lazy=%v: expected no bytes for %v, got %q", source.lazy, span, got)
+			}
+		}
+		if err := source.close(); err != nil {
+			t.Errorf("lazy=%v: close: %v", source.lazy, err)
+		}
+	}
+
+	missing := &fileSource{lazy: true, path: filepath.Join(t.TempDir(), "missing.go")}
+	if _, ok := missing.span(0, 1); ok || missing.close() == nil {
+		t.Errorf("expected an error reading a missing file")
+	}
+}
+
+func TestCollectNodesSourceShorterThanOffsets(t *testing.T) {
+	analyzer, err := NewGoAnalyzer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(analyzer.RepoAbs, "short.go")
+	content := "package short\n\n// Doc.\ntype T struct{}\n\nfunc F() {}\n"
+	file, err := parser.ParseFile(analyzer.FileSet, filename, content, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate source that does not match the parsed positions: the held
+	// content ends inside T's declaration.
+	analyzer.collectNodes(filename, &fileInfo{
+		file:    file,
+		pkgName: "short",
+		source:  &fileSource{data: []byte(content[:30])},
+	})
+
+	if len(analyzer.Nodes) != 2 {
+		t.Fatalf("expected nodes T and F, got %v", analyzer.Nodes)
+	}
+	for _, node := range analyzer.Nodes {
+		if node.SourceCode != "" || node.ContentHash != "" {
+			t.Errorf("%s: expected no source or hash, got %q and %q", node.ID, node.SourceCode, node.ContentHash)
+		}
+		if node.StartByte > node.EndByte {
+			t.Errorf("%s: expected StartByte <= EndByte, got %d > %d", node.ID, node.StartByte, node.EndByte)
+		}
+	}
+	if len(analyzer.Diagnostics) != 2 || !strings.Contains(analyzer.Diagnostics[0], "does not fit short.go") {
+		t.Errorf("expected a diagnostic per node, got %q", analyzer.Diagnostics)
+	}
+}
@@ -0,0 +1,143 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// DefaultStdlibInterfaces is a set of well-known standard library
+// interfaces for Options.StdlibInterfaces.
+var DefaultStdlibInterfaces = []string{
+	"error",
+	"fmt.Stringer",
+	"io.Reader",
+	"io.Writer",
+	"io.Closer",
+	"sort.Interface",
+	"encoding.TextMarshaler",
+	"encoding.TextUnmarshaler",
+	"encoding/json.Marshaler",
+	"encoding/json.Unmarshaler",
+}
+
+// splitStdlibInterface splits "encoding/json.Marshaler" into its import
+// path and type name. "error" has no package.
+func splitStdlibInterface(name string) (pkgPath, typeName string, ok bool) {
+	if name == "error" {
+		return "", name, true
+	}
+	i := strings.LastIndexByte(name, '.')
+	if i <= 0 || i == len(name)-1 {
+		return "", "", false
+	}
+	return name[:i], name[i+1:], true
+}
+
+// markStdlibInterfaces sets SatisfiesStdlib on the node of each repo-local
+// named type that implements, by value or by pointer, one of the interfaces
+// in StdlibInterfaces. Each interface is looked up among the packages the
+// type's package imports, directly or not, so that it is identical to the
+// types in its signatures; std packages the repo does not import are loaded
+// separately. Generic types are skipped.
+func (a *GoAnalyzer) markStdlibInterfaces() {
+	var loaded map[string]*types.Package // Lazily loaded std packages
+	lookup := func(pkg *types.Package, name string) *types.Interface {
+		pkgPath, typeName, _ := splitStdlibInterface(name)
+		scope := types.Universe
+		if pkgPath != "" {
+			std := findImport(pkg, pkgPath, map[*types.Package]bool{})
+			if std == nil {
+				if loaded == nil {
+					loaded = a.loadStdlibInterfaces()
+				}
+				std = loaded[pkgPath]
+			}
+			if std == nil {
+				return nil
+			}
+			scope = std.Scope()
+		}
+		obj, ok := scope.Lookup(typeName).(*types.TypeName)
+		if !ok {
+			return nil
+		}
+		iface, _ := obj.Type().Underlying().(*types.Interface)
+		return iface
+	}
+
+	missing := map[string]bool{}
+	for _, typeName := range a.repoTypeNames() {
+		named, ok := typeName.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			continue
+		}
+		i, ok := a.nodeIndex[a.getComponentIDForPos(typeName.Pos(), typeName.Name(), "")]
+		if !ok {
+			continue
+		}
+		var satisfied []string
+		for _, name := range a.StdlibInterfaces {
+			iface := lookup(typeName.Pkg(), name)
+			if iface == nil {
+				missing[name] = true
+				continue
+			}
+			if types.Implements(named, iface) || types.Implements(types.NewPointer(named), iface) {
+				satisfied = append(satisfied, name)
+			}
+		}
+		a.Nodes[i].SatisfiesStdlib = satisfied
+	}
+	for _, name := range a.StdlibInterfaces {
+		if missing[name] {
+			a.Diagnostics = append(a.Diagnostics, fmt.Sprintf("stdlib interface %s not found", name))
+		}
+	}
+}
+
+// findImport returns the package with path among pkg and its transitive
+// imports, or nil.
+func findImport(pkg *types.Package, path string, seen map[*types.Package]bool) *types.Package {
+	if pkg.Path() == path {
+		return pkg
+	}
+	seen[pkg] = true
+	for _, imp := range pkg.Imports() {
+		if seen[imp] {
+			continue
+		}
+		if found := findImport(imp, path, seen); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// loadStdlibInterfaces type-checks the packages named in StdlibInterfaces,
+// keyed by import path. Packages that fail to load are left out.
+func (a *GoAnalyzer) loadStdlibInterfaces() map[string]*types.Package {
+	var paths []string
+	for _, name := range a.StdlibInterfaces {
+		if pkgPath, _, _ := splitStdlibInterface(name); pkgPath != "" {
+			paths = append(paths, pkgPath)
+		}
+	}
+	loaded := map[string]*types.Package{}
+	if len(paths) == 0 {
+		return loaded
+	}
+	cfg := &packages.Config{Mode: packages.NeedName | packages.NeedTypes, Dir: a.RepoAbs}
+	pkgs, err := packages.Load(cfg, paths...)
+	if err != nil {
+		return loaded
+	}
+	for _, pkg := range pkgs {
+		if pkg.Types != nil && len(pkg.Errors) == 0 {
+			loaded[pkg.PkgPath] = pkg.Types
+		}
+	}
+	return loaded
+}
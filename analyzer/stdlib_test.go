@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStdlibInterfaces(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"types.go": `package testpkg
+
+import "io"
+
+type Temp struct{ C float64 }
+
+func (Temp) String() string { return "" }
+
+type Failure struct{}
+
+func (*Failure) Error() string { return "" }
+
+type Source struct{ r io.Reader }
+
+func (s Source) Read(p []byte) (int, error) { return s.r.Read(p) }
+
+func (s Source) Close() error { return nil }
+
+type Plain struct{}
+`})
+	analyzer.Reset()
+	analyzer.StdlibInterfaces = DefaultStdlibInterfaces
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	want := map[string][]string{
+		"types.Temp":    {"fmt.Stringer"}, // fmt is not imported by the repo
+		"types.Failure": {"error"},        // Pointer receiver
+		"types.Source":  {"io.Reader", "io.Closer"},
+		"types.Plain":   nil,
+	}
+	for _, node := range analyzer.Nodes {
+		if expected, ok := want[node.ID]; ok && !slices.Equal(node.SatisfiesStdlib, expected) {
+			t.Errorf("%s: expected %v, got %v", node.ID, expected, node.SatisfiesStdlib)
+		}
+	}
+	if len(analyzer.Diagnostics) > 0 {
+		t.Errorf("expected no diagnostics, got %v", analyzer.Diagnostics)
+	}
+
+	analyzer.Reset()
+	analyzer.StdlibInterfaces = []string{"Stringer"}
+	if err := analyzer.Analyze(); err == nil {
+		t.Error("expected an error for an interface without a package")
+	}
+}
@@ -0,0 +1,40 @@
+package analyzer
+
+import (
+	"path/filepath"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// countFans sets FanIn and FanOut on each node to its number of distinct
+// callers and callees over resolved "calls" and "dynamic_call"
+// relationships. Recursive calls are not counted.
+func (a *GoAnalyzer) countFans() {
+	seen := map[[2]string]bool{}
+	for _, rel := range a.Relationships {
+		edge := [2]string{rel.Caller, rel.Callee}
+		if !rel.IsResolved || !isCallEdge(rel) || seen[edge] {
+			continue
+		}
+		seen[edge] = true
+		if i, ok := a.nodeIndex[rel.Caller]; ok {
+			a.Nodes[i].FanOut++
+		}
+		if i, ok := a.nodeIndex[rel.Callee]; ok {
+			a.Nodes[i].FanIn++
+		}
+	}
+}
+
+// summarize fills Summary from the collected nodes and relationships. Each
+// directory of the analyzed filenames counts as one package.
+func (a *GoAnalyzer) summarize(filenames []string) {
+	dirs := map[string]bool{}
+	for _, filename := range filenames {
+		dirs[filepath.Dir(filename)] = true
+	}
+
+	summary := models.NewSummary(a.Nodes, a.Relationships)
+	summary.PackagesAnalyzed = len(dirs)
+	a.Summary = summary
+}
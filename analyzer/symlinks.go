@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// walkRepo is filepath.WalkDir over root that, with FollowSymlinks, also
+// descends into symlinked directories whose target lies outside the repo.
+// Paths under a link keep the link's location (repo/shared/x.go), so they
+// stay inside the repo root; fn sees the link itself as a directory.
+// Links into the repo are not followed, as their files are walked at their
+// real location, and each outside target is walked once, which also stops
+// cycles.
+func (a *GoAnalyzer) walkRepo(root string, fn fs.WalkDirFunc) error {
+	if !a.FollowSymlinks {
+		return filepath.WalkDir(root, fn)
+	}
+	repoReal, err := filepath.EvalSymlinks(a.RepoAbs)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	visited := map[string]bool{}
+
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.Type()&fs.ModeSymlink == 0 {
+				return fn(path, d, err)
+			}
+			target, info, ok := followableDir(path, repoReal, visited)
+			if !ok {
+				return fn(path, d, nil)
+			}
+			if err := fn(path, fs.FileInfoToDirEntry(info), nil); err != nil {
+				if err == filepath.SkipDir {
+					return nil
+				}
+				return err
+			}
+			visited[target] = true
+			return walkLinked(path, fn, walk)
+		})
+	}
+	return walk(root)
+}
+
+// walkLinked walks the children of the symlinked directory link with walk;
+// filepath.WalkDir would not descend into it as a root. Like WalkDir, it
+// calls fn again with the error when the directory cannot be read.
+func walkLinked(link string, fn fs.WalkDirFunc, walk func(dir string) error) error {
+	entries, err := os.ReadDir(link)
+	if err != nil {
+		return fn(link, nil, err)
+	}
+	for _, entry := range entries {
+		if err := walk(filepath.Join(link, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// followableDir resolves the symlink at path and reports whether it is a
+// directory outside repoReal that has not been walked yet.
+func followableDir(path string, repoReal string, visited map[string]bool) (string, fs.FileInfo, bool) {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil || visited[target] || isPathInRepo(repoReal, target) {
+		return "", nil, false
+	}
+	info, err := os.Stat(target)
+	if err != nil || !info.IsDir() {
+		return "", nil, false
+	}
+	return target, info, true
+}
+
+// symlinkPatterns returns "./link/..." patterns for the symlinked
+// directories followed under the module at root, which the go command's
+// "./..." does not descend into. Nested modules are left to their own
+// load.
+func (a *GoAnalyzer) symlinkPatterns(root string) ([]string, error) {
+	var patterns []string
+	err := a.walkRepo(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return &ReadError{Path: path, Err: err}
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		if isSkippedDir(d.Name()) || a.isIgnored(path, true) {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+			return filepath.SkipDir
+		}
+		if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			rel, _ := filepath.Rel(root, path)
+			patterns = append(patterns, "./"+filepath.ToSlash(rel)+"/...")
+		}
+		return nil
+	})
+	return patterns, err
+}
@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFollowSymlinks(t *testing.T) {
+	root := t.TempDir()
+	repoDir := filepath.Join(root, "repo")
+	sharedDir := filepath.Join(root, "shared")
+	files := map[string]string{
+		filepath.Join(repoDir, "main.go"):         "package main\n\nfunc main() {}\n",
+		filepath.Join(repoDir, "pkg", "pkg.go"):   "package pkg\n\nfunc Local() {}\n",
+		filepath.Join(sharedDir, "util.go"):       "package shared\n\nfunc Util() {}\n",
+		filepath.Join(sharedDir, "sub", "sub.go"): "package sub\n\nfunc Sub() {}\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeGoMod(t, repoDir)
+	links := map[string]string{
+		filepath.Join(repoDir, "shared"): sharedDir,                     // Outside the repo
+		filepath.Join(repoDir, "alias"):  filepath.Join(repoDir, "pkg"), // Inside the repo
+		filepath.Join(sharedDir, "loop"): sharedDir,                     // Cycle
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+
+	for _, follow := range []bool{false, true} {
+		analyzer, err := NewGoAnalyzer(repoDir)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		analyzer.FollowSymlinks = follow
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze failed (follow=%v): %v", follow, err)
+		}
+
+		ids := map[string]int{}
+		for _, node := range analyzer.Nodes {
+			ids[node.ID]++
+		}
+		for _, id := range []string{"shared.util.Util", "shared.sub.sub.Sub"} {
+			if (ids[id] == 1) != follow {
+				t.Errorf("follow=%v: unexpected count %d for %s in %v", follow, ids[id], id, ids)
+			}
+		}
+		if ids["pkg.pkg.Local"] != 1 || ids["alias.pkg.Local"] != 0 {
+			t.Errorf("follow=%v: expected the in-repo link to be skipped, got %v", follow, ids)
+		}
+		if len(ids) != len(analyzer.Nodes) {
+			t.Errorf("follow=%v: duplicate nodes in %v", follow, ids)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// testFuncKinds maps the name prefixes of the functions go test runs to
+// their TestKind and the testing type of their single parameter. Examples
+// take no parameters.
+var testFuncKinds = []struct {
+	prefix, kind, param string
+}{
+	{"Test", "test", "T"},
+	{"Benchmark", "benchmark", "B"},
+	{"Fuzz", "fuzz", "F"},
+	{"Example", "example", ""},
+}
+
+// testKind classifies fn, declared in a _test.go file, the way go test
+// does: by name prefix and signature, so TestMain(m *testing.M) or a
+// TestHelper taking other parameters is a plain function (""). The
+// signature is matched syntactically against the file's import of
+// "testing".
+func testKind(fn *ast.FuncDecl, file *ast.File) string {
+	if fn.Recv != nil || fn.Type.TypeParams != nil {
+		return ""
+	}
+	for _, k := range testFuncKinds {
+		if !isTestFuncName(fn.Name.Name, k.prefix) {
+			continue
+		}
+		params := fn.Type.Params.List
+		if k.param == "" {
+			if len(params) == 0 && fn.Type.Results == nil {
+				return k.kind
+			}
+			return ""
+		}
+		if len(params) != 1 || len(params[0].Names) > 1 {
+			return ""
+		}
+		star, ok := params[0].Type.(*ast.StarExpr)
+		if !ok {
+			return ""
+		}
+		sel, ok := star.X.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != k.param {
+			return ""
+		}
+		if x, ok := sel.X.(*ast.Ident); ok && x.Name == testingImportName(file) && fn.Type.Results == nil {
+			return k.kind
+		}
+		return ""
+	}
+	return ""
+}
+
+// isTestFuncName reports whether name is prefix followed by nothing or by
+// a character that is not a lower-case letter, so Testing is not a test.
+func isTestFuncName(name, prefix string) bool {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return rest == "" || !unicode.IsLower(r)
+}
+
+// testingImportName returns the name file binds the "testing" package to,
+// or "" if it does not import it.
+func testingImportName(file *ast.File) string {
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err != nil || path != "testing" {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+		return "testing"
+	}
+	return ""
+}
+
+// testVariants narrows packages loaded with tests to one variant per
+// package: "p [p.test]", which adds the _test.go files of p, replaces p,
+// and the generated "p.test" main packages are dropped. External test
+// packages ("p_test [p.test]") are kept.
+func testVariants(pkgs []*packages.Package) []*packages.Package {
+	hasVariant := map[string]bool{}
+	for _, pkg := range pkgs {
+		hasVariant[pkg.ID] = true
+	}
+	var kept []*packages.Package
+	for _, pkg := range pkgs {
+		if hasVariant[pkg.ID+" ["+pkg.PkgPath+".test]"] {
+			continue
+		}
+		if pkg.Name == "main" && strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		kept = append(kept, pkg)
+	}
+	return kept
+}
@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestIncludeTests(t *testing.T) {
+	files := map[string]string{
+		"calc.go": `package testpkg
+
+func Add(a, b int) int { return a + b }
+`,
+		"calc_test.go": `package testpkg
+
+import "testing"
+
+func TestAdd(t *testing.T) { Add(1, 2) }
+
+func BenchmarkAdd(b *testing.B) {
+	for b.Loop() {
+		Add(1, 2)
+	}
+}
+
+func TestMain(m *testing.M) {}
+
+func Testing(t *testing.T) {}
+`,
+		"calc_ext_test.go": `package testpkg_test
+
+import (
+	"fmt"
+	tt "testing"
+)
+
+func FuzzAdd(f *tt.F) {}
+
+func ExampleAdd() { fmt.Println(3) }
+`,
+	}
+	analyzer := analyzeFiles(t, files)
+	for _, node := range analyzer.Nodes {
+		if isTestFile(node.FilePath) {
+			t.Errorf("expected no nodes from test files by default, got %s", node.ID)
+		}
+	}
+
+	analyzer.Reset()
+	analyzer.IncludeTests = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	want := map[string]string{
+		"calc.Add":                 "",
+		"calc_test.TestAdd":        "test",
+		"calc_test.BenchmarkAdd":   "benchmark",
+		"calc_test.TestMain":       "",
+		"calc_test.Testing":        "",
+		"calc_ext_test.FuzzAdd":    "fuzz",
+		"calc_ext_test.ExampleAdd": "example",
+	}
+	got := map[string]string{}
+	for _, node := range analyzer.Nodes {
+		got[node.ID] = node.TestKind
+	}
+	for id, kind := range want {
+		if k, ok := got[id]; !ok || k != kind {
+			t.Errorf("%s: expected test kind %q, got %q (found: %v)", id, kind, k, ok)
+		}
+	}
+	resolved := false
+	for _, rel := range analyzer.Relationships {
+		if rel.Caller == "calc_test.TestAdd" && rel.Callee == "calc.Add" {
+			resolved = rel.IsResolved
+		}
+	}
+	if !resolved {
+		t.Errorf("expected a resolved call from TestAdd to Add, got %v", analyzer.Relationships)
+	}
+}
+
+func TestTestKindNeedsTestingSignature(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "x_test.go", `package x
+
+import "testing"
+
+func TestWrongType(t *testing.B) {}
+
+func TestResult(t *testing.T) error { return nil }
+
+func ExampleWithArg(s string) {}
+
+func Test(t *testing.T) {}
+`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"", "", "", "test"}
+	for i, decl := range file.Decls[1:] {
+		if got := testKind(decl.(*ast.FuncDecl), file); got != want[i] {
+			t.Errorf("decl %d: expected %q, got %q", i, want[i], got)
+		}
+	}
+}
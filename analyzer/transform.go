@@ -0,0 +1,284 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// NestMethods returns a copy of nodes in which each method whose owning type
+// node is present is moved from the top level into that node's Methods list.
+// IDs are unchanged, so relationships keep referring to the nested methods.
+func NestMethods(nodes []models.Node) []models.Node {
+	isClass := map[string]bool{}
+	for _, node := range nodes {
+		if node.ComponentType == "class" {
+			isClass[ownerKey(node, node.Name)] = true
+		}
+	}
+
+	top := make([]models.Node, 0, len(nodes))
+	classIndex := map[string]int{}
+	var methods []models.Node
+	for _, node := range nodes {
+		switch {
+		case node.ComponentType == "class":
+			classIndex[ownerKey(node, node.Name)] = len(top)
+			node.Methods = nil
+			top = append(top, node)
+		case node.ComponentType == "method" && isClass[ownerKey(node, node.ClassName)]:
+			methods = append(methods, node)
+		default:
+			top = append(top, node)
+		}
+	}
+
+	for _, method := range methods {
+		i := classIndex[ownerKey(method, method.ClassName)]
+		top[i].Methods = append(top[i].Methods, method)
+	}
+	return top
+}
+
+// ownerKey identifies a type by its package directory and name. Methods may
+// live in a different file than their type, so IDs cannot be compared.
+func ownerKey(node models.Node, typeName string) string {
+	return filepath.Join(filepath.Dir(node.RelativePath), typeName)
+}
+
+// FilterExported keeps only the exported API: nodes with Exported set,
+// minus methods whose receiver type is unexported and nodes marked
+// internal (see Options.InternalAsPrivate). Relationships from a
+// dropped node are removed; those into a dropped node are kept but marked
+// unresolved, so no resolved edge points outside the result.
+func FilterExported(nodes []models.Node, relationships []models.CallRelationship) ([]models.Node, []models.CallRelationship) {
+	kept := []models.Node{}
+	keptIDs := map[string]bool{}
+	for _, node := range nodes {
+		if !node.Exported || (node.ComponentType == "method" && !ast.IsExported(node.ClassName)) || node.Visibility == visibilityInternal {
+			continue
+		}
+		kept = append(kept, node)
+		keptIDs[node.ID] = true
+	}
+
+	rels := []models.CallRelationship{}
+	for _, rel := range relationships {
+		if !keptIDs[rel.Caller] {
+			continue
+		}
+		if !keptIDs[rel.Callee] {
+			rel.IsResolved = false
+		}
+		rels = append(rels, rel)
+	}
+	return kept, rels
+}
+
+// PruneReachable keeps only the part of result reachable from root within
+// maxDepth hops over resolved relationships (a negative maxDepth means no
+// limit). root may be a component ID or, if unambiguous, a node Name.
+// Unresolved relationships from kept nodes are kept as leaf edges.
+func PruneReachable(result models.AnalysisResult, root string, maxDepth int) (models.AnalysisResult, error) {
+	rootID, err := findRoot(result.Nodes, root)
+	if err != nil {
+		return result, err
+	}
+
+	nodeIDs := map[string]bool{}
+	for _, node := range result.Nodes {
+		nodeIDs[node.ID] = true
+	}
+	edges := map[string][]string{}
+	for _, rel := range result.CallRelationships {
+		if rel.IsResolved && nodeIDs[rel.Callee] {
+			edges[rel.Caller] = append(edges[rel.Caller], rel.Callee)
+		}
+	}
+
+	depth := map[string]int{rootID: 0}
+	queue := []string{rootID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if maxDepth >= 0 && depth[current] >= maxDepth {
+			continue
+		}
+		for _, next := range edges[current] {
+			if _, seen := depth[next]; !seen {
+				depth[next] = depth[current] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	pruned := result
+	pruned.Nodes = []models.Node{}
+	for _, node := range result.Nodes {
+		if _, ok := depth[node.ID]; ok {
+			pruned.Nodes = append(pruned.Nodes, node)
+		}
+	}
+	pruned.CallRelationships = []models.CallRelationship{}
+	for _, rel := range result.CallRelationships {
+		callerDepth, ok := depth[rel.Caller]
+		if !ok {
+			continue
+		}
+		if rel.IsResolved && nodeIDs[rel.Callee] {
+			if _, ok := depth[rel.Callee]; !ok {
+				continue
+			}
+		} else if maxDepth >= 0 && callerDepth >= maxDepth {
+			continue
+		}
+		pruned.CallRelationships = append(pruned.CallRelationships, rel)
+	}
+	return pruned, nil
+}
+
+// PruneCallers keeps only target and the nodes that call it, directly or
+// through other kept nodes, within maxDepth hops over the reverse call
+// index of CalledBy (a negative maxDepth means no limit): what a change to
+// target may affect. target may be a component ID or, if unambiguous, a
+// node Name. Only resolved relationships between kept nodes are kept.
+func PruneCallers(result models.AnalysisResult, target string, maxDepth int) (models.AnalysisResult, error) {
+	targetID, err := findRoot(result.Nodes, target)
+	if err != nil {
+		return result, err
+	}
+
+	calledBy := CalledBy(result.CallRelationships)
+	depth := map[string]int{targetID: 0}
+	queue := []string{targetID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if maxDepth >= 0 && depth[current] >= maxDepth {
+			continue
+		}
+		for _, caller := range calledBy[current] {
+			if _, seen := depth[caller]; !seen {
+				depth[caller] = depth[current] + 1
+				queue = append(queue, caller)
+			}
+		}
+	}
+
+	pruned := result
+	pruned.Nodes = []models.Node{}
+	for _, node := range result.Nodes {
+		if _, ok := depth[node.ID]; ok {
+			pruned.Nodes = append(pruned.Nodes, node)
+		}
+	}
+	pruned.CallRelationships = []models.CallRelationship{}
+	for _, rel := range result.CallRelationships {
+		_, callerKept := depth[rel.Caller]
+		_, calleeKept := depth[rel.Callee]
+		if rel.IsResolved && callerKept && calleeKept {
+			pruned.CallRelationships = append(pruned.CallRelationships, rel)
+		}
+	}
+	return pruned, nil
+}
+
+// DropUnresolved returns result without its unresolved relationships, such
+// as calls into the standard library or other modules, leaving the
+// intra-repo graph. The summary is left as is, so it still counts the
+// dropped relationships in UnresolvedRelationships.
+func DropUnresolved(result models.AnalysisResult) models.AnalysisResult {
+	relationships := []models.CallRelationship{}
+	for _, rel := range result.CallRelationships {
+		if rel.IsResolved {
+			relationships = append(relationships, rel)
+		}
+	}
+	result.CallRelationships = relationships
+	return result
+}
+
+// CalledBy builds a reverse call index: for each callee, the sorted,
+// deduplicated IDs of its callers over resolved "calls" and "dynamic_call"
+// relationships.
+func CalledBy(relationships []models.CallRelationship) map[string][]string {
+	callers := map[string][]string{}
+	for _, rel := range relationships {
+		if rel.IsResolved && isCallEdge(rel) {
+			callers[rel.Callee] = append(callers[rel.Callee], rel.Caller)
+		}
+	}
+	for callee, ids := range callers {
+		slices.Sort(ids)
+		callers[callee] = slices.Compact(ids)
+	}
+	return callers
+}
+
+// Entrypoints returns the sorted IDs of the program entrypoints among nodes:
+// func main of each main package and every init function. Nested methods
+// are not looked at, as entrypoints are never methods.
+func Entrypoints(nodes []models.Node) []string {
+	ids := []string{}
+	for _, node := range nodes {
+		if node.ComponentType != "function" || node.NodeType == "closure" {
+			continue
+		}
+		if (node.Name == "main" && node.PackageName == "main") || node.Name == "init" {
+			ids = append(ids, node.ID)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// RelationshipsOnly returns result with its nodes replaced by the sorted
+// list of their IDs, including those of nested methods, for callers that
+// already have the nodes and only want fresh relationships.
+func RelationshipsOnly(result models.AnalysisResult) models.AnalysisResult {
+	var ids []string
+	var collect func(nodes []models.Node)
+	collect = func(nodes []models.Node) {
+		for _, node := range nodes {
+			ids = append(ids, node.ID)
+			collect(node.Methods)
+		}
+	}
+	collect(result.Nodes)
+	slices.Sort(ids)
+
+	result.Nodes = []models.Node{}
+	result.NodeIDs = slices.Compact(ids)
+	return result
+}
+
+// isCallEdge reports whether rel is a static or dynamic call, as opposed to
+// a type relationship such as "produces" or "owns".
+func isCallEdge(rel models.CallRelationship) bool {
+	return rel.RelationshipType == "calls" || rel.RelationshipType == "dynamic_call"
+}
+
+// findRoot resolves a symbol given as a component ID or a unique Name.
+func findRoot(nodes []models.Node, symbol string) (string, error) {
+	var matches []string
+	for _, node := range nodes {
+		if node.ID == symbol {
+			return node.ID, nil
+		}
+		if node.Name == symbol {
+			matches = append(matches, node.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("symbol %q not found", symbol)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("symbol %q is ambiguous: %s", symbol, strings.Join(matches, ", "))
+	}
+}
@@ -0,0 +1,332 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+func TestNestMethods(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"store.go": `package testpkg
+
+type Store struct{}
+
+func (s *Store) Get() {}
+
+func Open() *Store { return &Store{} }
+`,
+		"store_methods.go": `package testpkg
+
+func (s *Store) Put() {}
+`,
+	})
+
+	nested := NestMethods(analyzer.Nodes)
+
+	var store *struct{ methods []string }
+	for _, node := range nested {
+		if node.ComponentType == "method" {
+			t.Errorf("Expected method %s to be nested, found at top level", node.ID)
+		}
+		if node.ID == "store.Store" {
+			store = &struct{ methods []string }{}
+			for _, m := range node.Methods {
+				store.methods = append(store.methods, m.ID)
+			}
+		}
+	}
+	if store == nil {
+		t.Fatal("Store node not found")
+	}
+	if len(store.methods) != 2 {
+		t.Errorf("Expected Get and Put nested under Store, got %v", store.methods)
+	}
+	if len(nested) != 2 {
+		t.Errorf("Expected Store and Open at top level, got %d nodes", len(nested))
+	}
+
+	// The flat result is left untouched.
+	for _, node := range analyzer.Nodes {
+		if len(node.Methods) != 0 {
+			t.Errorf("Expected flat node %s to have no nested methods", node.ID)
+		}
+	}
+}
+
+func TestPruneReachable(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"chain.go": `package testpkg
+
+import "fmt"
+
+func A() { B() }
+
+func B() {
+	C()
+	fmt.Println()
+}
+
+func C() { D() }
+
+func D() { fmt.Println() }
+
+func Unrelated() { A() }
+`,
+	})
+	result := models.NewAnalysisResult(analyzer.Nodes, analyzer.Relationships)
+
+	pruned, err := PruneReachable(result, "A", 2)
+	if err != nil {
+		t.Fatalf("PruneReachable failed: %v", err)
+	}
+
+	kept := map[string]bool{}
+	for _, node := range pruned.Nodes {
+		kept[node.ID] = true
+	}
+	if len(kept) != 3 || !kept["chain.A"] || !kept["chain.B"] || !kept["chain.C"] {
+		t.Errorf("Expected only A, B and C to remain, got %v", kept)
+	}
+	for _, rel := range pruned.CallRelationships {
+		if !kept[rel.Caller] {
+			t.Errorf("Relationship from pruned node kept: %+v", rel)
+		}
+		if rel.IsResolved && !kept[rel.Callee] {
+			t.Errorf("Resolved relationship to pruned node kept: %+v", rel)
+		}
+		if rel.Caller == "chain.C" && !rel.IsResolved {
+			t.Errorf("Expected no leaf edges beyond max depth, got %+v", rel)
+		}
+	}
+
+	foundLeaf := false
+	for _, rel := range pruned.CallRelationships {
+		if rel.Caller == "chain.B" && rel.Callee == "fmt.Println" {
+			foundLeaf = true
+		}
+	}
+	if !foundLeaf {
+		t.Error("Expected unresolved B -> fmt.Println to be kept as a leaf edge")
+	}
+
+	if _, err := PruneReachable(result, "Missing", 1); err == nil {
+		t.Error("Expected an error for an unknown root")
+	}
+}
+
+func TestFilterExported(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"api.go": `package testpkg
+
+type Client struct{}
+
+func (c *Client) Do() { c.retry() }
+
+func (c *Client) retry() { helper() }
+
+type conn struct{}
+
+func (c *conn) Close() {}
+
+func New() *Client {
+	helper()
+	return &Client{}
+}
+
+func helper() {}
+`,
+	})
+
+	nodes, rels := FilterExported(analyzer.Nodes, analyzer.Relationships)
+
+	ids := map[string]bool{}
+	for _, node := range nodes {
+		ids[node.ID] = true
+	}
+	for _, want := range []string{"api.Client", "api.Client.Do", "api.New"} {
+		if !ids[want] {
+			t.Errorf("Expected exported node %s to survive, got %v", want, ids)
+		}
+	}
+	for _, dropped := range []string{"api.Client.retry", "api.conn", "api.conn.Close", "api.helper"} {
+		if ids[dropped] {
+			t.Errorf("Expected %s to be dropped", dropped)
+		}
+	}
+
+	foundHelper := false
+	for _, rel := range rels {
+		if !ids[rel.Caller] {
+			t.Errorf("Expected no relationships from dropped node %s", rel.Caller)
+		}
+		if rel.IsResolved && !ids[rel.Callee] {
+			t.Errorf("Expected no resolved edge into dropped node: %+v", rel)
+		}
+		if rel.Caller == "api.New" && rel.Callee == "api.helper" {
+			foundHelper = true
+			if rel.IsResolved {
+				t.Error("Expected the call into the unexported helper to become unresolved")
+			}
+		}
+	}
+	if !foundHelper {
+		t.Errorf("Expected api.New -> api.helper to be kept as an unresolved edge, got %+v", rels)
+	}
+}
+
+func TestCalledBy(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"log.go": `package testpkg
+
+type Logger struct{}
+
+func NewLogger() *Logger { return &Logger{} }
+
+func write(s string) {}
+
+func Info(s string) { write(s); write(s) }
+
+func Warn(s string) { write(s); NewLogger() }
+`})
+
+	calledBy := CalledBy(analyzer.Relationships)
+	if got := calledBy["log.write"]; len(got) != 2 || got[0] != "log.Info" || got[1] != "log.Warn" {
+		t.Errorf("Expected log.write to be called by [log.Info log.Warn], got %v", got)
+	}
+	if got := calledBy["log.Logger"]; got != nil {
+		t.Errorf("Expected no callers from produces edges, got %v", got)
+	}
+}
+
+func TestRelationshipsOnly(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"log.go": `package testpkg
+
+type Logger struct{}
+
+func (l *Logger) Info(s string) { write(s) }
+
+func write(s string) {}
+`})
+	result := *analyzer.Result()
+	result.Nodes = NestMethods(result.Nodes)
+
+	trimmed := RelationshipsOnly(result)
+	data, err := json.Marshal(trimmed)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"nodes":[]`) || strings.Contains(string(data), "source_code") {
+		t.Errorf("Expected an empty nodes array, got %s", data)
+	}
+	if want := []string{"log.Logger", "log.Logger.Info", "log.write"}; !slices.Equal(trimmed.NodeIDs, want) {
+		t.Errorf("Expected node IDs %v, got %v", want, trimmed.NodeIDs)
+	}
+	if !slices.Equal(trimmed.CallRelationships, result.CallRelationships) || len(trimmed.CallRelationships) == 0 {
+		t.Errorf("Expected all relationships to be kept, got %v", trimmed.CallRelationships)
+	}
+}
+
+func TestEntrypoints(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{
+		"cmd/tool/main.go": `package main
+
+import "example.com/test/lib"
+
+func init() {}
+
+func main() { lib.Run() }
+`,
+		"lib/lib.go": `package lib
+
+var ready = setup()
+
+func setup() bool { return true }
+
+func init() {}
+
+func main() {}
+
+func Run() {}
+`,
+	})
+
+	want := []string{"cmd.tool.main.init#1", "cmd.tool.main.main", "lib.lib.init#1"}
+	if got := Entrypoints(analyzer.Nodes); !slices.Equal(got, want) {
+		t.Errorf("Expected entrypoints %v, got %v", want, got)
+	}
+}
+
+func TestPruneCallers(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"chain.go": `package testpkg
+
+func A() { B() }
+
+func B() { C() }
+
+func C() { D() }
+
+func D() {}
+
+func Other() { D() }
+`})
+	result := models.NewAnalysisResult(analyzer.Nodes, analyzer.Relationships)
+
+	kept := func(maxDepth int) []string {
+		t.Helper()
+		pruned, err := PruneCallers(result, "C", maxDepth)
+		if err != nil {
+			t.Fatalf("PruneCallers failed: %v", err)
+		}
+		var ids []string
+		for _, node := range pruned.Nodes {
+			ids = append(ids, node.ID)
+		}
+		for _, rel := range pruned.CallRelationships {
+			if rel.Callee == "chain.D" {
+				t.Errorf("Expected no edges out of the kept set, got %+v", rel)
+			}
+		}
+		slices.Sort(ids)
+		return ids
+	}
+	if got := kept(-1); !slices.Equal(got, []string{"chain.A", "chain.B", "chain.C"}) {
+		t.Errorf("Expected A, B and C, got %v", got)
+	}
+	if got := kept(1); !slices.Equal(got, []string{"chain.B", "chain.C"}) {
+		t.Errorf("Expected B and C within one hop, got %v", got)
+	}
+
+	if _, err := PruneCallers(result, "Missing", -1); err == nil {
+		t.Error("Expected an error for an unknown symbol")
+	}
+}
+
+func TestDropUnresolved(t *testing.T) {
+	analyzer := analyzeFiles(t, map[string]string{"greet.go": `package testpkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+func Greet(name string) { fmt.Println(format(name)) }
+
+func format(name string) string { return strings.ToUpper(name) }
+`})
+	result := DropUnresolved(*analyzer.Result())
+
+	if len(result.CallRelationships) == 0 {
+		t.Fatal("Expected the resolved relationships to be kept")
+	}
+	for _, rel := range result.CallRelationships {
+		if !rel.IsResolved {
+			t.Errorf("Expected only resolved relationships, got %+v", rel)
+		}
+	}
+	if got := result.Summary.UnresolvedRelationships; got != 2 {
+		t.Errorf("Expected the summary to count 2 unresolved relationships, got %d", got)
+	}
+}
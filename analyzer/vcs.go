@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/mod/semver"
+)
+
+// loadVCSInfo sets GitCommit to the commit checked out in the git
+// repository containing the repo, and ModuleVersion to the highest semver
+// tag on that commit for the module at the repo root, the version the go
+// command would give this commit. The repository is read directly, without
+// a git binary. Both stay empty outside a git checkout or before the first
+// commit.
+func (a *GoAnalyzer) loadVCSInfo() error {
+	a.GitCommit, a.ModuleVersion = "", ""
+	repo, err := git.PlainOpenWithOptions(a.RepoAbs, &git.PlainOpenOptions{DetectDotGit: true})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	a.GitCommit = head.Hash().String()
+
+	if _, err := os.Stat(filepath.Join(a.RepoAbs, "go.mod")); err != nil {
+		return nil
+	}
+	// A module in a subdirectory is versioned by tags prefixed with its
+	// path, e.g. sub/v1.2.0.
+	prefix := ""
+	if worktree, err := repo.Worktree(); err == nil {
+		if rel, err := filepath.Rel(worktree.Filesystem.Root(), a.RepoAbs); err == nil && rel != "." {
+			prefix = filepath.ToSlash(rel) + "/"
+		}
+	}
+
+	tags, err := repo.Tags()
+	if err != nil {
+		return err
+	}
+	return tags.ForEach(func(ref *plumbing.Reference) error {
+		version, ok := strings.CutPrefix(ref.Name().Short(), prefix)
+		if !ok || !semver.IsValid(version) || semver.Compare(version, a.ModuleVersion) <= 0 {
+			return nil
+		}
+		target := ref.Hash()
+		if tag, err := repo.TagObject(target); err == nil {
+			target = tag.Target // Annotated tag
+		}
+		if target == head.Hash() {
+			a.ModuleVersion = version
+		}
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package analyzer
+
+import "testing"
+
+func TestVCSInfo(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+	const other = "89abcdef0123456789abcdef0123456789abcdef"
+	repoDir := t.TempDir()
+	writeGoMod(t, repoDir)
+	files := map[string]string{
+		"main.go":                   "package main\n\nfunc main() {}\n",
+		".git/HEAD":                 "ref: refs/heads/main\n",
+		".git/refs/heads/main":      commit + "\n",
+		".git/refs/tags/v0.9.0":     commit + "\n",
+		".git/refs/tags/v1.2.0":     commit + "\n",
+		".git/refs/tags/v1.10.0":    other + "\n",  // Not on HEAD
+		".git/refs/tags/nightly":    commit + "\n", // Not semver
+		".git/refs/tags/sub/v2.0.0": commit + "\n", // Another module's
+	}
+	writeFiles(t, repoDir, files)
+
+	analyzer, err := NewGoAnalyzer(repoDir)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	analyzer.VCSInfo = true
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if analyzer.GitCommit != commit {
+		t.Errorf("expected GitCommit %s, got %q", commit, analyzer.GitCommit)
+	}
+	if analyzer.ModuleVersion != "v1.2.0" {
+		t.Errorf("expected ModuleVersion v1.2.0, got %q", analyzer.ModuleVersion)
+	}
+
+	// Outside a git checkout both stay empty.
+	plain := analyzeFiles(t, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	plain.Reset()
+	plain.VCSInfo = true
+	if err := plain.Analyze(); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if plain.GitCommit != "" || plain.ModuleVersion != "" {
+		t.Errorf("expected no VCS info outside a checkout, got %q %q", plain.GitCommit, plain.ModuleVersion)
+	}
+}
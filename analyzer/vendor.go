@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// skipsDir reports whether directories named name are left out of the
+// walks over the repo's files: isSkippedDir, except for vendor with
+// IncludeVendor.
+func (a *GoAnalyzer) skipsDir(name string) bool {
+	return isSkippedDir(name) && !(a.IncludeVendor && name == "vendor")
+}
+
+// includesVendorPackage reports whether the vendored package importPath is
+// selected by VendorPackages: all are when it is empty.
+func (a *GoAnalyzer) includesVendorPackage(importPath string) bool {
+	if len(a.VendorPackages) == 0 {
+		return true
+	}
+	for _, selected := range a.VendorPackages {
+		if importPath == selected || strings.HasPrefix(importPath, selected+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// vendoredFile reports whether the repo file path lies in a vendor
+// directory and, if so, whether its package is selected for analysis. The
+// import path of a vendored package is its directory below vendor/.
+func (a *GoAnalyzer) vendoredFile(path string) (vendored, included bool) {
+	rel, err := filepath.Rel(a.RepoAbs, filepath.Dir(path))
+	if err != nil {
+		return false, false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i, part := range parts {
+		if part == "vendor" {
+			return true, a.IncludeVendor && a.includesVendorPackage(strings.Join(parts[i+1:], "/"))
+		}
+	}
+	return false, false
+}
+
+// hasVendor reports whether the module at root has a vendor directory the
+// go command can load from.
+func hasVendor(root string) bool {
+	_, err := os.Stat(filepath.Join(root, "vendor", "modules.txt"))
+	return err == nil
+}
+
+// vendorPatterns returns the import paths of the selected packages listed
+// in the vendor/modules.txt of the module at root, which "./..." leaves
+// out. A module without a vendor directory has none.
+func (a *GoAnalyzer) vendorPatterns(root string) ([]string, error) {
+	f, err := os.Open(filepath.Join(root, "vendor", "modules.txt"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &ReadError{Path: filepath.Join(root, "vendor", "modules.txt"), Err: err}
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines starting with # describe modules; the others are packages.
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") && a.includesVendorPackage(line) {
+			patterns = append(patterns, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &ReadError{Path: f.Name(), Err: err}
+	}
+	return patterns, nil
+}
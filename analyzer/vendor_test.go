@@ -0,0 +1,81 @@
+package analyzer
+
+import "testing"
+
+func TestIncludeVendor(t *testing.T) {
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                            "module example.com/test\n\ngo 1.25\n\nrequire example.com/lib v1.0.0\n",
+		"main.go":                           "package main\n\nimport \"example.com/lib\"\n\nfunc main() { lib.Help() }\n",
+		"vendor/modules.txt":                "# example.com/lib v1.0.0\n## explicit; go 1.25\nexample.com/lib\nexample.com/lib/sub\n",
+		"vendor/example.com/lib/lib.go":     "package lib\n\nfunc Help() {}\n",
+		"vendor/example.com/lib/sub/sub.go": "package sub\n\nfunc Sub() {}\n",
+	}
+	writeFiles(t, repoDir, files)
+
+	const help, sub = "vendor.example.com.lib.lib.Help", "vendor.example.com.lib.sub.sub.Sub"
+	tests := []struct {
+		name     string
+		include  bool
+		packages []string
+		want     map[string]bool
+	}{
+		{"default", false, nil, map[string]bool{help: false, sub: false}},
+		{"all", true, nil, map[string]bool{help: true, sub: true}},
+		{"selected", true, []string{"example.com/lib/sub"}, map[string]bool{help: false, sub: true}},
+	}
+	for _, tt := range tests {
+		// The go command picks vendor mode by itself unless GOFLAGS says
+		// otherwise; IncludeVendor must not depend on it.
+		if tt.include {
+			t.Setenv("GOFLAGS", "-mod=mod")
+		} else {
+			t.Setenv("GOFLAGS", "-mod=vendor")
+		}
+		analyzer, err := NewGoAnalyzer(repoDir)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		analyzer.IncludeVendor = tt.include
+		analyzer.VendorPackages = tt.packages
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("%s: Analyze failed: %v", tt.name, err)
+		}
+		for id, want := range tt.want {
+			if analyzer.CollectedNodeIDs[id] != want {
+				t.Errorf("%s: expected node %s: %v, got %v", tt.name, id, want, analyzer.CollectedNodeIDs)
+			}
+		}
+		resolved := false
+		for _, rel := range analyzer.Relationships {
+			if rel.Caller == "main.main" && rel.Callee == help && rel.IsResolved {
+				resolved = true
+			}
+		}
+		if resolved != tt.want[help] {
+			t.Errorf("%s: expected the call to the vendored function resolved: %v, got %+v", tt.name, tt.want[help], analyzer.Relationships)
+		}
+	}
+}
+
+func TestIncludeVendorWithoutModule(t *testing.T) {
+	repoDir := t.TempDir()
+	writeFiles(t, repoDir, map[string]string{
+		"main.go":                       "package main\n\nfunc main() {}\n",
+		"vendor/example.com/lib/lib.go": "package lib\n\nfunc Help() {}\n",
+	})
+
+	for _, include := range []bool{false, true} {
+		analyzer, err := NewGoAnalyzer(repoDir)
+		if err != nil {
+			t.Fatalf("Failed to create analyzer: %v", err)
+		}
+		analyzer.IncludeVendor = include
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze failed: %v", err)
+		}
+		if analyzer.CollectedNodeIDs["vendor.example.com.lib.lib.Help"] != include {
+			t.Errorf("include=%v: unexpected nodes %v", include, analyzer.CollectedNodeIDs)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package analyzer
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// visibilityInternal is Node.Visibility for nodes in internal/ packages.
+const visibilityInternal = "internal"
+
+// markInternal sets Visibility on nodes under an internal/ directory and
+// IntoInternal on relationships reaching them from outside internal/.
+func (a *GoAnalyzer) markInternal() {
+	for i := range a.Nodes {
+		if isInternalPath(a.Nodes[i].RelativePath) {
+			a.Nodes[i].Visibility = visibilityInternal
+		}
+	}
+	for i, rel := range a.Relationships {
+		if !a.isInternalNode(rel.Caller) && a.isInternalNode(rel.Callee) {
+			a.Relationships[i].IntoInternal = true
+		}
+	}
+}
+
+func (a *GoAnalyzer) isInternalNode(id string) bool {
+	i, ok := a.nodeIndex[id]
+	return ok && a.Nodes[i].Visibility == visibilityInternal
+}
+
+// isInternalPath reports whether the file at relativePath is in a package
+// under an internal/ directory, following the go command's rule.
+func isInternalPath(relativePath string) bool {
+	return slices.Contains(strings.Split(path.Dir(slashPath(relativePath)), "/"), "internal")
+}
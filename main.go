@@ -1,45 +1,329 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/don7panic/codewiki-go-analyzer/analyzer"
 	"github.com/don7panic/codewiki-go-analyzer/models"
+	"github.com/don7panic/codewiki-go-analyzer/output"
 )
 
+// stringList is a flag.Value that accumulates each occurrence of a
+// repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// describeError explains an analysis error in terms of what the user can
+// check.
+func describeError(err error) string {
+	var readErr *analyzer.ReadError
+	var loadErr *analyzer.LoadError
+	switch {
+	case errors.As(err, &readErr):
+		return fmt.Sprintf("cannot read %s (check --repo): %v", readErr.Path, readErr.Err)
+	case errors.As(err, &loadErr):
+		return fmt.Sprintf("the go command could not load packages in %s: %v", loadErr.Dir, loadErr.Err)
+	case errors.Is(err, analyzer.ErrNoPackages):
+		return fmt.Sprintf("%v (check the package patterns and --file values)", err)
+	default:
+		return fmt.Sprintf("analysis failed: %v", err)
+	}
+}
+
+// gitRefAnalysis runs the analysis an is configured for on the tree of a
+// git ref instead of the working tree.
+type gitRefAnalysis struct {
+	an     *analyzer.GoAnalyzer
+	ref    string
+	result *models.AnalysisResult
+}
+
+func (g *gitRefAnalysis) Analyze() error {
+	return g.AnalyzeContext(context.Background())
+}
+
+func (g *gitRefAnalysis) AnalyzeContext(ctx context.Context) error {
+	result, err := analyzer.AnalyzeGitRefContext(ctx, g.an.RepoPath, g.ref, func(a *analyzer.GoAnalyzer) {
+		a.Options = g.an.Options
+		a.ProgressFunc = g.an.ProgressFunc
+		a.Logger = g.an.Logger
+	})
+	if result != nil {
+		g.result = result
+	}
+	return err
+}
+
+func (g *gitRefAnalysis) Result() *models.AnalysisResult {
+	if g.result == nil {
+		return g.an.Result()
+	}
+	return g.result
+}
+
+func (g *gitRefAnalysis) DeadCodeCandidates() []string {
+	if g.result == nil {
+		return g.an.DeadCodeCandidates()
+	}
+	return analyzer.DeadCode(g.result.Nodes, g.result.CallRelationships, g.an.DeadCodeExported)
+}
+
 func main() {
+	var files stringList
+	flag.Var(&files, "file", "Only output nodes and relationships from this file (repeatable; relative to --repo)")
 	repoPath := flag.String("repo", "", "Path to the repository root")
+	noSource := flag.Bool("no-source", false, "Omit source_code from nodes")
+	lazySource := flag.Bool("lazy-source", false, "Read each declaration's source from disk when it is emitted instead of holding all files in memory")
+	maxSource := flag.Int("max-source-bytes", 0, "Truncate source_code longer than this many bytes (0 means no limit)")
+	nativePaths := flag.Bool("native-paths", false, "Keep OS path separators in file_path and relative_path")
+	dispatch := flag.Bool("interface-dispatch", false, "Add dynamic_call edges from interface method calls to repo-local implementations")
+	deadline := flag.Duration("deadline", 0, "Stop analysis after this duration and print partial results")
+	timeout := flag.Duration("timeout", 0, "Fail if analysis takes longer than this duration")
+	externalFields := flag.Bool("external-fields", false, "Also add has_field edges to field types from outside the repo")
+	packageGraph := flag.Bool("package-graph", false, "Emit a node per package and imports edges between repo packages")
+	externalImports := flag.Bool("external-imports", false, "With --package-graph, also add imports edges to packages outside the repo")
+	goGenerate := flag.Bool("go-generate", false, "Record each file's //go:generate directives in go_generate")
+	packageDocs := flag.Bool("package-docs", false, "Record each package's doc comment in package_docs")
+	imports := flag.Bool("imports", false, "Record each file's imports (name -> import path)")
+	jobs := flag.Int("jobs", 0, "Load at most this many module roots at once (default GOMAXPROCS)")
+	verbose := flag.Bool("verbose", false, "Print analysis progress to stderr")
+	logLevel := flag.String("log-level", "", "Log to stderr at this level: debug, info, warn or error (default no logging)")
+	root := flag.String("root", "", "Only output what is reachable from this symbol (component ID or name)")
+	callersOf := flag.String("callers-of", "", "Only output this symbol (component ID or name) and what calls it, directly or transitively")
+	maxDepth := flag.Int("max-depth", -1, "Maximum number of hops from --root or --callers-of (negative means unlimited)")
+	nested := flag.Bool("nested", false, "Nest method nodes under their owning type")
+	followSymlinks := flag.Bool("follow-symlinks", false, "Descend into symlinked directories that point outside the repo")
+	gitRef := flag.String("git-ref", "", "Analyze the tree of this branch, tag or commit instead of the working tree")
+	vcsInfo := flag.Bool("vcs-info", false, "Record the repo's git commit and module version tag in the output")
+	includeVendor := flag.Bool("include-vendor", false, "Also analyze vendored packages")
+	vendorPackages := flag.String("vendor-packages", "", "Comma-separated import paths limiting --include-vendor to these packages and those below them")
+	includeTests := flag.Bool("include-tests", false, "Also analyze _test.go files and mark test functions with test_kind")
+	excludeGenerated := flag.Bool("exclude-generated", false, "Skip files with a \"Code generated ... DO NOT EDIT.\" header")
+	useGitignore := flag.Bool("gitignore", false, "Skip files and directories matched by the repo's .gitignore files")
+	lineComments := flag.Bool("line-comments", false, "Record comments trailing a declaration on its last line in line_comment")
+	localTypes := flag.Bool("local-types", false, "Emit types declared inside function bodies")
+	markStdlib := flag.Bool("mark-stdlib", false, "Mark calls into the standard library with is_stdlib")
+	closures := flag.Bool("closures", false, "Emit function literals as closure nodes and attribute their calls to them")
+	stdlibInterfaces := flag.Bool("stdlib-interfaces", false, "Record which well-known standard library interfaces (fmt.Stringer, error, io.Reader, ...) each type implements")
+	rels := flag.String("rels", "", "Comma-separated relationship types to collect (default all)")
+	idStyle := flag.String("id-style", analyzer.IDStyleDottedPath, "Component ID style: dotted-path, slash-path or import-path")
+	modulePrefix := flag.String("module-prefix", "", "Prefix prepended to every component ID, e.g. a module path")
+	internalPrivate := flag.Bool("internal-private", false, "Mark nodes in internal/ packages as internal and drop them with --exported-only")
+	exportedOnly := flag.Bool("exported-only", false, "Only output exported declarations")
+	cacheDir := flag.String("cache-dir", "", "Cache results in this directory and reuse those of unchanged modules (default no caching)")
+	noCache := flag.Bool("no-cache", false, "Do not read or write cached results, even with --cache-dir")
+	onlyResolved := flag.Bool("only-resolved", false, "Drop unresolved relationships (calls outside the repo, ...) from the output")
+	includeReverse := flag.Bool("include-reverse", false, "Add a called_by index from each callee to its callers")
+	entrypoints := flag.Bool("entrypoints", false, "List func main of main packages and init functions in entrypoints")
+	deadCode := flag.Bool("dead-code", false, "List functions and methods no resolved call points at")
+	deadCodeExported := flag.Bool("dead-code-exported", false, "With --dead-code, also list exported functions and methods")
+	relationshipsOnly := flag.Bool("relationships-only", false, "Replace the nodes with a node_ids list and only output relationships")
+	compact := flag.Bool("compact", false, "Print JSON on a single line instead of indented")
+	splitByPackage := flag.Bool("split-by-package", false, "Write one JSON file per package and an index.json to --out-dir instead of stdout")
+	out := flag.String("out", "", "Write the output to this file instead of stdout")
+	outDir := flag.String("out-dir", "", "Output directory for --split-by-package")
+	format := flag.String("format", "json", "Output format: json, cytoscape, adjacency or mermaid")
 	flag.Parse()
 
 	if *repoPath == "" {
-		fmt.Println("Error: --repo argument is required")
+		fmt.Fprintln(os.Stderr, "Error: --repo argument is required")
+		os.Exit(1)
+	}
+	if *format != "json" && *format != "cytoscape" && *format != "adjacency" && *format != "mermaid" {
+		fmt.Fprintf(os.Stderr, "Error: unknown --format %q\n", *format)
 		os.Exit(1)
 	}
 
+	if *splitByPackage && (*outDir == "" || *format != "json") {
+		fmt.Fprintln(os.Stderr, "Error: --split-by-package requires --out-dir and --format json")
+		os.Exit(1)
+	}
+	if *relationshipsOnly && (*splitByPackage || *format != "json") {
+		fmt.Fprintln(os.Stderr, "Error: --relationships-only requires --format json and cannot be combined with --split-by-package")
+		os.Exit(1)
+	}
+	if *splitByPackage && *out != "" {
+		fmt.Fprintln(os.Stderr, "Error: --out cannot be combined with --split-by-package")
+		os.Exit(1)
+	}
+
+	var level slog.Level
+	if *logLevel != "" {
+		if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: unknown --log-level %q\n", *logLevel)
+			os.Exit(1)
+		}
+	}
+
 	an, err := analyzer.NewGoAnalyzer(*repoPath)
 	if err != nil {
-		fmt.Printf("Error creating analyzer: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error creating analyzer: %v\n", err)
 		os.Exit(1)
 	}
+	an.IncludeSource = !*noSource
+	an.MaxSourceBytes = *maxSource
+	an.LazySource = *lazySource
+	an.SlashPaths = !*nativePaths
+	an.ResolveInterfaceDispatch = *dispatch
+	an.ExternalFieldTypes = *externalFields
+	an.CollectImports = *imports
+	an.PackageGraph = *packageGraph
+	an.ExternalImports = *externalImports
+	an.CollectPackageDocs = *packageDocs
+	an.CollectGenerate = *goGenerate
+	an.MarkStdlib = *markStdlib
+	an.ExtractLocalTypes = *localTypes
+	an.LineComments = *lineComments
+	an.UseGitignore = *useGitignore
+	an.ExcludeGenerated = *excludeGenerated
+	an.IncludeTests = *includeTests
+	an.Jobs = *jobs
+	an.IncludeVendor = *includeVendor || *vendorPackages != ""
+	if *vendorPackages != "" {
+		an.VendorPackages = strings.Split(*vendorPackages, ",")
+	}
+	an.FollowSymlinks = *followSymlinks
+	an.VCSInfo = *vcsInfo
+	an.ExtractClosures = *closures
+	an.IDStyle = *idStyle
+	if *stdlibInterfaces {
+		an.StdlibInterfaces = analyzer.DefaultStdlibInterfaces
+	}
+	if *rels != "" {
+		an.RelationshipTypes = strings.Split(*rels, ",")
+	}
+	an.ModulePrefix = *modulePrefix
+	an.ExportedOnly = *exportedOnly
+	an.InternalAsPrivate = *internalPrivate
+	an.DeadCodeExported = *deadCodeExported
+	an.Patterns = flag.Args()
+	an.Files = files
+	if !*noCache {
+		an.CacheDir = *cacheDir
+	}
+	if *verbose {
+		an.ProgressFunc = func(stage string, current, total int) {
+			fmt.Fprintf(os.Stderr, "%s: %d/%d\n", stage, current, total)
+		}
+	}
 
-	if err := an.Analyze(); err != nil {
-		fmt.Printf("Error analyzing file: %v\n", err)
-		os.Exit(1)
+	if *logLevel != "" {
+		an.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
 	}
 
-	result := models.AnalysisResult{
-		Nodes:             an.Nodes,
-		CallRelationships: an.Relationships,
+	ctx := context.Background()
+	if *deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *deadline)
+		defer cancel()
+	}
+	// Unlike --deadline, --timeout is a hard limit: hitting it is an error.
+	errTimeout := fmt.Errorf("analysis timed out after %s", *timeout)
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, *timeout, errTimeout)
+		defer cancel()
 	}
 
-	output, err := json.MarshalIndent(result, "", "  ")
+	// Past configuration, only the Analyzer interface is used.
+	var analysis analyzer.Analyzer = an
+	if *gitRef != "" {
+		analysis = &gitRefAnalysis{an: an, ref: *gitRef}
+	}
+	err = analysis.AnalyzeContext(ctx)
+	result := *analysis.Result()
 	if err != nil {
-		fmt.Printf("Error marshaling output: %v\n", err)
-		os.Exit(1)
+		if errors.Is(context.Cause(ctx), errTimeout) {
+			err = errTimeout
+		}
+		if !result.Partial || !errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", describeError(err))
+			os.Exit(1)
+		}
+	}
+	for _, diag := range result.Diagnostics {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", diag)
+	}
+	if dc, ok := analysis.(interface{ DeadCodeCandidates() []string }); ok && *deadCode {
+		result.Unreferenced = dc.DeadCodeCandidates()
+	}
+	if *root != "" {
+		result, err = analyzer.PruneReachable(result, *root, *maxDepth)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error pruning from --root: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *callersOf != "" {
+		result, err = analyzer.PruneCallers(result, *callersOf, *maxDepth)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error pruning to --callers-of: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *onlyResolved {
+		result = analyzer.DropUnresolved(result)
+	}
+	if *entrypoints {
+		result.Entrypoints = analyzer.Entrypoints(result.Nodes)
+	}
+	if *includeReverse {
+		result.CalledBy = analyzer.CalledBy(result.CallRelationships)
+	}
+	if *nested {
+		result.Nodes = analyzer.NestMethods(result.Nodes)
+	}
+	if *relationshipsOnly {
+		result = analyzer.RelationshipsOnly(result)
+	}
+
+	if *splitByPackage {
+		if err := output.WriteSplit(*outDir, result, *compact); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing --out-dir: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	fmt.Println(string(output))
+	var data []byte
+	if *format == "mermaid" {
+		data = []byte(strings.TrimSuffix(output.ToMermaid(result), "\n"))
+	} else {
+		var v any = result
+		switch *format {
+		case "cytoscape":
+			v = output.ToCytoscape(result)
+		case "adjacency":
+			v = output.ToAdjacency(result)
+		}
+		data, err = output.MarshalJSON(v, *compact)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error marshaling output: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *out != "" {
+		if err := output.WriteFile(*out, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing --out: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d nodes and %d relationships to %s\n", len(result.Nodes), len(result.CallRelationships), *out)
+		return
+	}
+	fmt.Println(string(data))
 }
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+)
+
+// NewSummary counts nodes and relationships. PackagesAnalyzed is left for
+// the caller, which knows what was loaded.
+func NewSummary(nodes []Node, relationships []CallRelationship) Summary {
+	summary := Summary{
+		TotalNodes:         len(nodes),
+		TotalRelationships: len(relationships),
+	}
+	for _, node := range nodes {
+		switch node.NodeType {
+		case "function":
+			summary.Functions++
+		case "method":
+			summary.Methods++
+		case "struct":
+			summary.Structs++
+		case "interface":
+			summary.Interfaces++
+		case "const":
+			summary.Constants++
+		case "var":
+			summary.Variables++
+		}
+	}
+	for _, rel := range relationships {
+		if rel.IsResolved {
+			summary.ResolvedRelationships++
+		} else {
+			summary.UnresolvedRelationships++
+		}
+	}
+	return summary
+}
+
+// Merge combines results from separate analyses, e.g. of different modules,
+// into one:
+//
+//   - Nodes are deduplicated by ID, keeping the first one unless a later
+//     one has SourceCode and it does not. Nodes sharing an ID but differing
+//     in content hash or source add a diagnostic. Fan-in and fan-out are
+//     not recomputed.
+//   - Relationships are deduplicated, after marking those whose callee is
+//     a merged node as resolved.
+//   - Diagnostics are concatenated; imports, go:generate directives,
+//     package docs, entrypoints and called_by entries are combined;
+//     unreferenced IDs that a resolved call now points at are dropped.
+//   - The summary is recomputed, counting packages by PackagePath (or
+//     directory).
+//
+// The result has the current schema version; the first non-empty git
+// commit and module version are kept.
+func Merge(results ...AnalysisResult) AnalysisResult {
+	merged := NewAnalysisResult([]Node{}, []CallRelationship{})
+
+	index := map[string]int{}
+	for _, result := range results {
+		for _, node := range result.Nodes {
+			i, ok := index[node.ID]
+			if !ok {
+				index[node.ID] = len(merged.Nodes)
+				merged.Nodes = append(merged.Nodes, node)
+				continue
+			}
+			existing := merged.Nodes[i]
+			if nodesConflict(existing, node) {
+				merged.Diagnostics = append(merged.Diagnostics, fmt.Sprintf("conflicting nodes for %s in %s and %s", node.ID, existing.RelativePath, node.RelativePath))
+			}
+			if existing.SourceCode == "" && node.SourceCode != "" {
+				merged.Nodes[i] = node
+			}
+		}
+	}
+
+	seen := map[CallRelationship]bool{}
+	referenced := map[string]bool{}
+	for _, result := range results {
+		for _, rel := range result.CallRelationships {
+			if _, ok := index[rel.Callee]; ok {
+				rel.IsResolved = true
+			}
+			if seen[rel] {
+				continue
+			}
+			seen[rel] = true
+			merged.CallRelationships = append(merged.CallRelationships, rel)
+			if rel.IsResolved && (rel.RelationshipType == "calls" || rel.RelationshipType == "dynamic_call") {
+				referenced[rel.Callee] = true
+			}
+		}
+	}
+
+	unreferenced := map[string]bool{}
+	entrypoints := map[string]bool{}
+	for _, result := range results {
+		merged.Partial = merged.Partial || result.Partial
+		merged.Diagnostics = append(merged.Diagnostics, result.Diagnostics...)
+		if merged.GitCommit == "" {
+			merged.GitCommit = result.GitCommit
+		}
+		if merged.ModuleVersion == "" {
+			merged.ModuleVersion = result.ModuleVersion
+		}
+		for file, imports := range result.Imports {
+			if merged.Imports == nil {
+				merged.Imports = map[string]map[string]string{}
+			}
+			merged.Imports[file] = imports
+		}
+		for file, directives := range result.Generate {
+			if merged.Generate == nil {
+				merged.Generate = map[string][]GenerateDirective{}
+			}
+			merged.Generate[file] = directives
+		}
+		for pkg, doc := range result.PackageDocs {
+			if merged.PackageDocs == nil {
+				merged.PackageDocs = map[string]string{}
+			}
+			if _, ok := merged.PackageDocs[pkg]; !ok {
+				merged.PackageDocs[pkg] = doc
+			}
+		}
+		for callee, callers := range result.CalledBy {
+			if merged.CalledBy == nil {
+				merged.CalledBy = map[string][]string{}
+			}
+			merged.CalledBy[callee] = append(merged.CalledBy[callee], callers...)
+		}
+		for _, id := range result.Unreferenced {
+			if !referenced[id] {
+				unreferenced[id] = true
+			}
+		}
+		for _, id := range result.Entrypoints {
+			entrypoints[id] = true
+		}
+	}
+	for callee, callers := range merged.CalledBy {
+		sort.Strings(callers)
+		merged.CalledBy[callee] = slices.Compact(callers)
+	}
+	if len(unreferenced) > 0 {
+		merged.Unreferenced = make([]string, 0, len(unreferenced))
+		for id := range unreferenced {
+			merged.Unreferenced = append(merged.Unreferenced, id)
+		}
+		sort.Strings(merged.Unreferenced)
+	}
+	if len(entrypoints) > 0 {
+		merged.Entrypoints = make([]string, 0, len(entrypoints))
+		for id := range entrypoints {
+			merged.Entrypoints = append(merged.Entrypoints, id)
+		}
+		sort.Strings(merged.Entrypoints)
+	}
+
+	packages := map[string]bool{}
+	for _, node := range merged.Nodes {
+		if node.PackagePath != "" {
+			packages[node.PackagePath] = true
+		} else {
+			packages[path.Dir(node.RelativePath)] = true
+		}
+	}
+	summary := NewSummary(merged.Nodes, merged.CallRelationships)
+	summary.PackagesAnalyzed = len(packages)
+	merged.Summary = &summary
+	return merged
+}
+
+// nodesConflict reports whether a and b, which share an ID, describe
+// different declarations, judged by the content hash or else the source
+// when both have one.
+func nodesConflict(a, b Node) bool {
+	if a.ContentHash != "" && b.ContentHash != "" {
+		return a.ContentHash != b.ContentHash
+	}
+	return a.SourceCode != "" && b.SourceCode != "" && a.SourceCode != b.SourceCode
+}
@@ -1,24 +1,101 @@
 // Package models contains the data structures used by the analyzer.
 package models
 
+const (
+	// ToolName identifies this analyzer in AnalysisResult.GeneratedBy.
+	ToolName = "codewiki-go-analyzer"
+	// ToolVersion is the analyzer release version.
+	ToolVersion = "0.1.0"
+	// SchemaVersion is the version of the JSON output format. Bump it
+	// whenever fields are added to or changed in the output.
+	SchemaVersion = "1.37"
+)
+
+// Values of CallRelationship.Confidence, from most to least certain.
+const (
+	// ConfidenceExact: the type checker identified the callee, a repo
+	// declaration named by its component ID.
+	ConfidenceExact = "exact"
+	// ConfidencePackageQualified: the type checker identified a callee
+	// outside the repo, named pkg.Func or pkg.Type.Method (builtins by
+	// their name).
+	ConfidencePackageQualified = "package-qualified"
+	// ConfidenceHeuristic: the callee was guessed from names without type
+	// information, is named after the type of a func-typed field or the
+	// constraint of a type parameter, is one of the possible targets of
+	// an interface call, or is the function a package-level variable is
+	// initialized to.
+	ConfidenceHeuristic = "heuristic"
+)
+
 type Node struct {
-	ID            string   `json:"id"`
-	Name          string   `json:"name"`
-	ComponentType string   `json:"component_type"`
-	FilePath      string   `json:"file_path"`
-	RelativePath  string   `json:"relative_path"`
-	DependsOn     []string `json:"depends_on"`
-	SourceCode    string   `json:"source_code,omitempty"`
-	StartLine     int      `json:"start_line"`
-	EndLine       int      `json:"end_line"`
-	HasDocstring  bool     `json:"has_docstring"`
-	Docstring     string   `json:"docstring"`
-	Parameters    []string `json:"parameters,omitempty"`
-	NodeType      string   `json:"node_type,omitempty"`
-	BaseClasses   []string `json:"base_classes,omitempty"`
-	ClassName     string   `json:"class_name,omitempty"`
-	DisplayName   string   `json:"display_name,omitempty"`
-	ComponentID   string   `json:"component_id,omitempty"`
+	ID               string      `json:"id"`
+	Name             string      `json:"name"`
+	ComponentType    string      `json:"component_type"`
+	FilePath         string      `json:"file_path"`
+	RelativePath     string      `json:"relative_path"`
+	PackageName      string      `json:"package_name,omitempty"` // Name in the package clause, e.g. "main" in cmd/tool
+	PackagePath      string      `json:"package_path,omitempty"` // Import path of the declaring package; empty without a module
+	DependsOn        []string    `json:"depends_on"`
+	SourceCode       string      `json:"source_code,omitempty"`
+	SourceTruncated  bool        `json:"source_truncated,omitempty"` // SourceCode was cut at Options.MaxSourceBytes
+	ContentHash      string      `json:"content_hash,omitempty"`     // sha256 of the token-normalized source span
+	StartLine        int         `json:"start_line"`
+	EndLine          int         `json:"end_line"`
+	StartCol         int         `json:"start_col"` // Columns pair with StartLine/EndLine
+	EndCol           int         `json:"end_col"`
+	StartByte        int         `json:"start_byte"` // Byte span of SourceCode, including any doc comment
+	EndByte          int         `json:"end_byte"`
+	HasDocstring     bool        `json:"has_docstring"`
+	Docstring        string      `json:"docstring"`
+	LineComment      string      `json:"line_comment,omitempty"`     // Trailing comment on the declaration's last line; see Options.LineComments
+	Deprecated       bool        `json:"deprecated,omitempty"`       // The doc comment has a "Deprecated:" paragraph
+	DeprecationNote  string      `json:"deprecation_note,omitempty"` // Text of that paragraph
+	Parameters       []string    `json:"parameters,omitempty"`
+	NodeType         string      `json:"node_type,omitempty"`
+	BaseClasses      []string    `json:"base_classes,omitempty"`     // Structs only: embedded types, e.g. "Base" or "io.Reader"
+	SatisfiesStdlib  []string    `json:"satisfies_stdlib,omitempty"` // Types only: implemented interfaces from Options.StdlibInterfaces
+	ClassName        string      `json:"class_name,omitempty"`
+	ReceiverType     string      `json:"receiver_type,omitempty"`  // Methods only: receiver as written, e.g. "*Stack[T]"
+	OwnerTypeID      string      `json:"owner_type_id,omitempty"`  // Methods only: ID of the receiver type's node
+	EnclosingFunc    string      `json:"enclosing_func,omitempty"` // Function-local types only: ID of the declaring function
+	DisplayName      string      `json:"display_name,omitempty"`
+	ShortName        string      `json:"short_name,omitempty"` // Functions and methods: "func Name" or "method T.Name"
+	ComponentID      string      `json:"component_id,omitempty"`
+	IsRecursive      bool        `json:"is_recursive,omitempty"`
+	TestKind         string      `json:"test_kind,omitempty"`    // "test", "benchmark", "fuzz" or "example"; functions in _test.go files only
+	FanIn            int         `json:"fan_in,omitempty"`       // Distinct resolved callers
+	FanOut           int         `json:"fan_out,omitempty"`      // Distinct resolved callees
+	IsInterface      bool        `json:"is_interface,omitempty"` // NodeType is "interface"; class nodes only
+	Exported         bool        `json:"exported"`
+	Visibility       string      `json:"visibility,omitempty"`        // "internal" under an internal/ directory; see Options.InternalAsPrivate
+	Methods          []Node      `json:"methods,omitempty"`           // Only set in nested output
+	InterfaceMethods []MethodSig `json:"interface_methods,omitempty"` // Interfaces only: full method set
+	Fields           []FieldInfo `json:"fields,omitempty"`            // Structs only: fields in declaration order
+}
+
+// FieldInfo describes one field of a struct. Fields declared together, as
+// in "a, b int", each get an entry with the group's type and comments.
+type FieldInfo struct {
+	Name     string `json:"name"` // For embedded fields, the type name, e.g. "Reader" for *io.Reader
+	Type     string `json:"type"` // As written, e.g. "map[string]*Item"
+	Embedded bool   `json:"embedded,omitempty"`
+	Doc      string `json:"doc,omitempty"`     // Comment on the lines above the field
+	Comment  string `json:"comment,omitempty"` // Comment trailing the field on its line
+}
+
+// MethodSig describes one method in an interface's method set.
+type MethodSig struct {
+	Name               string `json:"name"`
+	Signature          string `json:"signature"`           // Parameters and results, e.g. "(p []byte) (int, error)"
+	DeclaringInterface string `json:"declaring_interface"` // Node ID, or a qualified name like "io.Reader" outside the repo
+}
+
+// GenerateDirective is a //go:generate comment found in a file.
+type GenerateDirective struct {
+	Directive string `json:"directive"` // Verbatim, e.g. "//go:generate stringer -type=Kind"
+	Line      int    `json:"line"`
+	Before    string `json:"before,omitempty"` // ID of the next top-level declaration in the file
 }
 
 type CallRelationship struct {
@@ -27,9 +104,55 @@ type CallRelationship struct {
 	CallLine         int    `json:"call_line,omitempty"`
 	IsResolved       bool   `json:"is_resolved"`
 	RelationshipType string `json:"relationship_type,omitempty"`
+	IsStdlib         bool   `json:"is_stdlib,omitempty"`     // Callee is in the standard library; see Options.MarkStdlib
+	IntoInternal     bool   `json:"into_internal,omitempty"` // Caller outside internal/ uses an internal node; see Options.InternalAsPrivate
+	Confidence       string `json:"confidence,omitempty"`    // How the callee was determined, for calls, recurses and dynamic_call; see ConfidenceExact
 }
 
 type AnalysisResult struct {
-	Nodes             []Node             `json:"nodes"`
-	CallRelationships []CallRelationship `json:"call_relationships"`
+	SchemaVersion     string                         `json:"schema_version"`
+	GeneratedBy       string                         `json:"generated_by"`
+	Nodes             []Node                         `json:"nodes"`
+	NodeIDs           []string                       `json:"node_ids,omitempty"` // Sorted node IDs, in place of Nodes in relationships-only output
+	CallRelationships []CallRelationship             `json:"call_relationships"`
+	Partial           bool                           `json:"partial,omitempty"`
+	Diagnostics       []string                       `json:"diagnostics,omitempty"`
+	Imports           map[string]map[string]string   `json:"imports,omitempty"`      // Relative file path -> import name -> import path
+	PackageDocs       map[string]string              `json:"package_docs,omitempty"` // Package path -> package doc comment
+	Generate          map[string][]GenerateDirective `json:"go_generate,omitempty"`  // Relative file path -> //go:generate directives
+	Unreferenced      []string                       `json:"unreferenced,omitempty"` // Dead-code candidates, see GoAnalyzer.DeadCodeCandidates
+	Entrypoints       []string                       `json:"entrypoints,omitempty"`  // IDs of func main in main packages and of init functions
+	CalledBy          map[string][]string            `json:"called_by,omitempty"`    // Callee ID -> sorted caller IDs
+	Summary           *Summary                       `json:"summary,omitempty"`
+	GitCommit         string                         `json:"git_commit,omitempty"`     // Commit the repo had checked out
+	ModuleVersion     string                         `json:"module_version,omitempty"` // Semver tag on GitCommit
+}
+
+// Summary counts what an analysis emitted. Node counts are by NodeType;
+// closures, var_init and package nodes only count towards TotalNodes. The
+// analyzer does not emit const or var nodes yet, so Constants and Variables
+// are zero.
+type Summary struct {
+	TotalNodes              int `json:"total_nodes"`
+	Functions               int `json:"functions"`
+	Methods                 int `json:"methods"`
+	Structs                 int `json:"structs"`
+	Interfaces              int `json:"interfaces"`
+	Constants               int `json:"constants"`
+	Variables               int `json:"variables"`
+	TotalRelationships      int `json:"total_relationships"`
+	ResolvedRelationships   int `json:"resolved_relationships"`
+	UnresolvedRelationships int `json:"unresolved_relationships"`
+	PackagesAnalyzed        int `json:"packages_analyzed"`
+}
+
+// NewAnalysisResult builds an AnalysisResult stamped with the current
+// schema version and tool identity.
+func NewAnalysisResult(nodes []Node, relationships []CallRelationship) AnalysisResult {
+	return AnalysisResult{
+		SchemaVersion:     SchemaVersion,
+		GeneratedBy:       ToolName + "/" + ToolVersion,
+		Nodes:             nodes,
+		CallRelationships: relationships,
+	}
 }
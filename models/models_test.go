@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAnalysisResultVersion(t *testing.T) {
+	result := NewAnalysisResult([]Node{}, []CallRelationship{})
+
+	output, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(output, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if v, _ := decoded["schema_version"].(string); v == "" {
+		t.Errorf("Expected non-empty schema_version, got %v", decoded["schema_version"])
+	}
+	if v, _ := decoded["generated_by"].(string); !strings.HasPrefix(v, ToolName+"/") {
+		t.Errorf("Expected generated_by to start with %s/, got %v", ToolName, decoded["generated_by"])
+	}
+	if !strings.HasPrefix(string(output), `{"schema_version":`) {
+		t.Errorf("Expected schema_version to be emitted first, got %s", output)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	shared := Node{ID: "lib.Help", NodeType: "function", PackagePath: "example.com/lib", ContentHash: "h1"}
+	withSource := shared
+	withSource.SourceCode = "func Help() {}"
+	a := NewAnalysisResult(
+		[]Node{{ID: "app.main", NodeType: "function", PackagePath: "example.com/app"}, shared},
+		[]CallRelationship{
+			{Caller: "app.main", Callee: "lib.Help", IsResolved: true, RelationshipType: "calls"},
+			{Caller: "app.main", Callee: "fmt.Println", RelationshipType: "calls"},
+		},
+	)
+	b := NewAnalysisResult(
+		[]Node{withSource, {ID: "lib.T", NodeType: "struct", PackagePath: "example.com/lib"}},
+		[]CallRelationship{
+			{Caller: "app.main", Callee: "lib.Help", IsResolved: true, RelationshipType: "calls"},
+			{Caller: "lib.Help", Callee: "lib.T", IsResolved: true, RelationshipType: "produces"},
+		},
+	)
+	b.Diagnostics = []string{"from b"}
+
+	merged := Merge(a, b)
+	if len(merged.Nodes) != 3 {
+		t.Fatalf("Expected 3 nodes, got %+v", merged.Nodes)
+	}
+	if merged.Nodes[1].ID != "lib.Help" || merged.Nodes[1].SourceCode == "" {
+		t.Errorf("Expected the lib.Help node with source code, got %+v", merged.Nodes[1])
+	}
+	if len(merged.CallRelationships) != 3 {
+		t.Errorf("Expected the union of 3 relationships, got %+v", merged.CallRelationships)
+	}
+	if len(merged.Diagnostics) != 1 || merged.Diagnostics[0] != "from b" {
+		t.Errorf("Expected only the input diagnostics, got %v", merged.Diagnostics)
+	}
+	want := Summary{TotalNodes: 3, Functions: 2, Structs: 1, TotalRelationships: 3, ResolvedRelationships: 2, UnresolvedRelationships: 1, PackagesAnalyzed: 2}
+	if merged.Summary == nil || *merged.Summary != want {
+		t.Errorf("Expected summary %+v, got %+v", want, merged.Summary)
+	}
+
+	conflicting := withSource
+	conflicting.ContentHash = "h2"
+	merged = Merge(a, NewAnalysisResult([]Node{conflicting}, nil))
+	if len(merged.Nodes) != 2 || len(merged.Diagnostics) != 1 || !strings.Contains(merged.Diagnostics[0], "lib.Help") {
+		t.Errorf("Expected a diagnostic for the conflicting lib.Help, got %v", merged.Diagnostics)
+	}
+}
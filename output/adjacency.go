@@ -0,0 +1,64 @@
+package output
+
+import (
+	"sort"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// AdjacencyGraph is a flattened adjacency-list view of a result. Maps are
+// marshaled with sorted keys and every callee list is sorted and
+// deduplicated, so the output is deterministic.
+type AdjacencyGraph struct {
+	Nodes     map[string]AdjacencyNode `json:"nodes"`
+	Adjacency map[string][]string      `json:"adjacency"` // Caller ID -> resolved callee IDs
+	External  map[string][]string      `json:"_external"` // Caller ID -> unresolved callee names
+}
+
+type AdjacencyNode struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ToAdjacency converts a result into an adjacency list. Resolved
+// relationships between nodes in the result go into Adjacency; unresolved
+// ones from a node in the result go into External.
+func ToAdjacency(result models.AnalysisResult) AdjacencyGraph {
+	graph := AdjacencyGraph{
+		Nodes:     map[string]AdjacencyNode{},
+		Adjacency: map[string][]string{},
+		External:  map[string][]string{},
+	}
+	for _, node := range result.Nodes {
+		graph.Nodes[node.ID] = AdjacencyNode{Name: node.Name, Type: node.NodeType}
+	}
+
+	for _, rel := range result.CallRelationships {
+		if _, ok := graph.Nodes[rel.Caller]; !ok {
+			continue
+		}
+		if _, ok := graph.Nodes[rel.Callee]; ok && rel.IsResolved {
+			graph.Adjacency[rel.Caller] = append(graph.Adjacency[rel.Caller], rel.Callee)
+		} else if !rel.IsResolved {
+			graph.External[rel.Caller] = append(graph.External[rel.Caller], rel.Callee)
+		}
+	}
+
+	for _, lists := range []map[string][]string{graph.Adjacency, graph.External} {
+		for caller, callees := range lists {
+			lists[caller] = sortedUnique(callees)
+		}
+	}
+	return graph
+}
+
+func sortedUnique(values []string) []string {
+	sort.Strings(values)
+	unique := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,75 @@
+// Package output converts analysis results into alternative graph formats
+// for consumption by visualization tools.
+package output
+
+import "github.com/don7panic/codewiki-go-analyzer/models"
+
+type CytoscapeGraph struct {
+	Elements CytoscapeElements `json:"elements"`
+}
+
+type CytoscapeElements struct {
+	Nodes []CytoscapeNode `json:"nodes"`
+	Edges []CytoscapeEdge `json:"edges"`
+}
+
+type CytoscapeNode struct {
+	Data CytoscapeNodeData `json:"data"`
+}
+
+type CytoscapeNodeData struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+}
+
+type CytoscapeEdge struct {
+	Data CytoscapeEdgeData `json:"data"`
+}
+
+type CytoscapeEdgeData struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Type     string `json:"type"`
+	Resolved bool   `json:"resolved"`
+}
+
+// ToCytoscape converts a result into Cytoscape.js elements JSON. Only
+// resolved relationships are included, so every edge connects two nodes
+// present in the graph.
+func ToCytoscape(result models.AnalysisResult) CytoscapeGraph {
+	graph := CytoscapeGraph{
+		Elements: CytoscapeElements{
+			Nodes: []CytoscapeNode{},
+			Edges: []CytoscapeEdge{},
+		},
+	}
+
+	nodeIDs := map[string]bool{}
+	for _, node := range result.Nodes {
+		nodeIDs[node.ID] = true
+		graph.Elements.Nodes = append(graph.Elements.Nodes, CytoscapeNode{
+			Data: CytoscapeNodeData{
+				ID:    node.ID,
+				Label: node.Name,
+				Type:  node.NodeType,
+			},
+		})
+	}
+
+	for _, rel := range result.CallRelationships {
+		if !rel.IsResolved || !nodeIDs[rel.Caller] || !nodeIDs[rel.Callee] {
+			continue
+		}
+		graph.Elements.Edges = append(graph.Elements.Edges, CytoscapeEdge{
+			Data: CytoscapeEdgeData{
+				Source:   rel.Caller,
+				Target:   rel.Callee,
+				Type:     rel.RelationshipType,
+				Resolved: rel.IsResolved,
+			},
+		})
+	}
+
+	return graph
+}
@@ -0,0 +1,25 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// MarshalJSON encodes v for stdout: indented with two spaces for people to
+// read, or on a single line when compact is set for other programs.
+func MarshalJSON(v any, compact bool) ([]byte, error) {
+	if compact {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// WriteFile writes data, plus a trailing newline as on stdout, to the file
+// name, creating its parent directories as needed.
+func WriteFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(data, '\n'), 0644)
+}
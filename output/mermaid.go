@@ -0,0 +1,73 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// mermaidLabel escapes characters Mermaid would otherwise parse inside a
+// quoted node label.
+var mermaidLabel = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+
+// ToMermaid renders a result as a Mermaid "graph LR" diagram. Component IDs
+// contain characters Mermaid cannot take in node IDs, so each node gets a
+// sanitized ID (letters, digits and underscores, with a numeric suffix on
+// collisions) and a leading %% comment maps it back to the full ID. Like
+// ToCytoscape, only resolved relationships between nodes in the result are
+// drawn; edges other than "calls" are labeled with their type.
+func ToMermaid(result models.AnalysisResult) string {
+	ids := map[string]string{}
+	taken := map[string]bool{}
+	for _, node := range result.Nodes {
+		if _, ok := ids[node.ID]; ok {
+			continue
+		}
+		id := mermaidID(node.ID)
+		for i := 2; taken[id]; i++ {
+			id = fmt.Sprintf("%s_%d", mermaidID(node.ID), i)
+		}
+		taken[id] = true
+		ids[node.ID] = id
+	}
+
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for _, node := range result.Nodes {
+		fmt.Fprintf(&b, "    %%%% %s = %s\n", ids[node.ID], node.ID)
+	}
+	for _, node := range result.Nodes {
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", ids[node.ID], mermaidLabel.Replace(node.Name))
+	}
+
+	seen := map[string]bool{}
+	for _, rel := range result.CallRelationships {
+		caller, ok := ids[rel.Caller]
+		callee, ok2 := ids[rel.Callee]
+		if !rel.IsResolved || !ok || !ok2 {
+			continue
+		}
+		edge := fmt.Sprintf("%s -->|%s| %s", caller, rel.RelationshipType, callee)
+		if rel.RelationshipType == "calls" {
+			edge = fmt.Sprintf("%s --> %s", caller, callee)
+		}
+		if seen[edge] {
+			continue
+		}
+		seen[edge] = true
+		fmt.Fprintf(&b, "    %s\n", edge)
+	}
+	return b.String()
+}
+
+// mermaidID replaces every character of a component ID that is not an ASCII
+// letter or digit with an underscore.
+func mermaidID(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, id)
+}
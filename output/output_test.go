@@ -0,0 +1,286 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+func sampleResult() models.AnalysisResult {
+	return models.NewAnalysisResult(
+		[]models.Node{
+			{ID: "pkg.A", Name: "A", ComponentType: "function", NodeType: "function"},
+			{ID: "pkg.B", Name: "B", ComponentType: "function", NodeType: "function"},
+			{ID: "pkg.T", Name: "T", ComponentType: "class", NodeType: "struct"},
+		},
+		[]models.CallRelationship{
+			{Caller: "pkg.A", Callee: "pkg.B", IsResolved: true, RelationshipType: "calls"},
+			{Caller: "pkg.A", Callee: "fmt.Println", IsResolved: false, RelationshipType: "calls"},
+			{Caller: "pkg.B", Callee: "pkg.T", IsResolved: true, RelationshipType: "produces"},
+		},
+	)
+}
+
+func TestToCytoscape(t *testing.T) {
+	data, err := json.Marshal(ToCytoscape(sampleResult()))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded struct {
+		Elements struct {
+			Nodes []struct {
+				Data map[string]any `json:"data"`
+			} `json:"nodes"`
+			Edges []struct {
+				Data map[string]any `json:"data"`
+			} `json:"edges"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if len(decoded.Elements.Nodes) != 3 {
+		t.Fatalf("Expected 3 nodes, got %d", len(decoded.Elements.Nodes))
+	}
+	ids := map[string]bool{}
+	for _, node := range decoded.Elements.Nodes {
+		for _, key := range []string{"id", "label", "type"} {
+			if _, ok := node.Data[key]; !ok {
+				t.Errorf("Node data missing %q: %v", key, node.Data)
+			}
+		}
+		ids[node.Data["id"].(string)] = true
+	}
+
+	if len(decoded.Elements.Edges) != 2 {
+		t.Fatalf("Expected 2 resolved edges, got %d", len(decoded.Elements.Edges))
+	}
+	for _, edge := range decoded.Elements.Edges {
+		source, _ := edge.Data["source"].(string)
+		target, _ := edge.Data["target"].(string)
+		if !ids[source] || !ids[target] {
+			t.Errorf("Edge %s -> %s references a missing node", source, target)
+		}
+		if resolved, _ := edge.Data["resolved"].(bool); !resolved {
+			t.Errorf("Expected only resolved edges, got %v", edge.Data)
+		}
+		if _, ok := edge.Data["type"]; !ok {
+			t.Errorf("Edge data missing type: %v", edge.Data)
+		}
+	}
+}
+
+func TestMarshalJSONCompact(t *testing.T) {
+	compact, err := MarshalJSON(sampleResult(), true)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	if bytes.ContainsAny(compact, "\n") {
+		t.Errorf("Expected compact output on a single line, got %s", compact)
+	}
+	if !json.Valid(compact) {
+		t.Errorf("Expected compact output to be valid JSON, got %s", compact)
+	}
+
+	pretty, err := MarshalJSON(sampleResult(), false)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	if !bytes.Contains(pretty, []byte("\n  \"schema_version\"")) {
+		t.Errorf("Expected indented output by default, got %s", pretty)
+	}
+}
+
+func TestToAdjacency(t *testing.T) {
+	result := sampleResult()
+	result.CallRelationships = append(result.CallRelationships,
+		models.CallRelationship{Caller: "pkg.A", Callee: "pkg.B", IsResolved: true, RelationshipType: "calls"},
+		models.CallRelationship{Caller: "pkg.A", Callee: "pkg.T", IsResolved: true, RelationshipType: "produces"},
+	)
+	data, err := json.Marshal(ToAdjacency(result))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded struct {
+		Nodes     map[string]map[string]string `json:"nodes"`
+		Adjacency map[string][]string          `json:"adjacency"`
+		External  map[string][]string          `json:"_external"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if got := strings.Join(decoded.Adjacency["pkg.A"], ","); got != "pkg.B,pkg.T" {
+		t.Errorf("Expected pkg.A -> [pkg.B pkg.T] sorted and deduplicated, got %v", decoded.Adjacency["pkg.A"])
+	}
+	if got := strings.Join(decoded.External["pkg.A"], ","); got != "fmt.Println" {
+		t.Errorf("Expected fmt.Println under _external for pkg.A, got %v", decoded.External)
+	}
+	if decoded.Nodes["pkg.T"]["type"] != "struct" {
+		t.Errorf("Expected a nodes index entry for pkg.T, got %v", decoded.Nodes)
+	}
+
+	again, _ := json.Marshal(ToAdjacency(result))
+	if string(again) != string(data) {
+		t.Error("Expected deterministic output")
+	}
+}
+
+func TestToMermaid(t *testing.T) {
+	result := sampleResult()
+	result.Nodes = append(result.Nodes, models.Node{ID: "pkg/A", Name: "<init>", ComponentType: "function"})
+	result.CallRelationships = append(result.CallRelationships,
+		models.CallRelationship{Caller: "pkg.A", Callee: "pkg.B", IsResolved: true, RelationshipType: "calls"},
+	)
+	got := ToMermaid(result)
+
+	for _, want := range []string{
+		"graph LR\n",
+		"%% pkg_A = pkg.A\n",
+		"%% pkg_A_2 = pkg/A\n",
+		"pkg_A[\"A\"]\n",
+		"pkg_A_2[\"#lt;init#gt;\"]\n",
+		"pkg_A --> pkg_B\n",
+		"pkg_B -->|produces| pkg_T\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "pkg_A --> pkg_B") != 1 {
+		t.Errorf("Expected duplicate edges to be drawn once, got:\n%s", got)
+	}
+	if strings.Contains(got, "fmt") {
+		t.Errorf("Expected unresolved edges to be left out, got:\n%s", got)
+	}
+}
+
+func TestWriteSplit(t *testing.T) {
+	result := models.NewAnalysisResult(
+		[]models.Node{
+			{ID: "main.main", Name: "main", PackagePath: "example.com/m"},
+			{ID: "util.util.Help", Name: "Help", PackagePath: "example.com/m/util"},
+			{ID: "util.util.T", Name: "T", PackagePath: "example.com/m/util"},
+		},
+		[]models.CallRelationship{
+			{Caller: "main.main", Callee: "util.util.Help", IsResolved: true, RelationshipType: "calls"},
+			{Caller: "util.util.Help", Callee: "util.util.T", IsResolved: true, RelationshipType: "produces"},
+			{Caller: "util.util.Help", Callee: "fmt.Println", RelationshipType: "calls"},
+		},
+	)
+	dir := t.TempDir()
+	if err := WriteSplit(dir, result, false); err != nil {
+		t.Fatalf("WriteSplit failed: %v", err)
+	}
+
+	read := func(name string, v any) {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("Unmarshal %s failed: %v", name, err)
+		}
+	}
+	var index SplitIndex
+	read(SplitIndexFile, &index)
+	want := []SplitPackage{
+		{Package: "example.com/m", File: "packages/example.com/m.json"},
+		{Package: "example.com/m/util", File: "packages/example.com/m/util.json"},
+	}
+	if !slices.Equal(index.Packages, want) {
+		t.Fatalf("Expected index packages %v, got %v", want, index.Packages)
+	}
+
+	callers := map[string][]string{
+		"packages/example.com/m.json":      {"main.main"},
+		"packages/example.com/m/util.json": {"util.util.Help", "util.util.Help"},
+	}
+	for name, want := range callers {
+		var part models.AnalysisResult
+		read(name, &part)
+		var got []string
+		for _, rel := range part.CallRelationships {
+			got = append(got, rel.Caller)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: expected relationships from %v, got %v", name, want, got)
+		}
+		for _, node := range part.Nodes {
+			if name != "packages/"+node.PackagePath+".json" {
+				t.Errorf("%s: unexpected node %s from %s", name, node.ID, node.PackagePath)
+			}
+		}
+	}
+}
+
+func TestWriteSplitIndexPackage(t *testing.T) {
+	// A module-less repo with a top-level index/ directory has a package
+	// named like the index file.
+	result := models.NewAnalysisResult(
+		[]models.Node{
+			{ID: "index.index.Build", Name: "Build", RelativePath: "index/index.go"},
+			{ID: "main.main", Name: "main", RelativePath: "main.go"},
+		},
+		[]models.CallRelationship{},
+	)
+	dir := t.TempDir()
+	if err := WriteSplit(dir, result, false); err != nil {
+		t.Fatalf("WriteSplit failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, SplitIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index SplitIndex
+	if err := json.Unmarshal(data, &index); err != nil || len(index.Packages) != 2 {
+		t.Fatalf("expected an index of 2 packages, got %s (%v)", data, err)
+	}
+	for _, pkg := range index.Packages {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(pkg.File)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var part models.AnalysisResult
+		if err := json.Unmarshal(data, &part); err != nil || len(part.Nodes) != 1 {
+			t.Errorf("%s: expected 1 node in %s, got %s (%v)", pkg.Package, pkg.File, data, err)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	data, err := MarshalJSON(sampleResult(), false)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	name := filepath.Join(t.TempDir(), "nested", "dir", "result.json")
+	if err := WriteFile(name, data); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	written, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result models.AnalysisResult
+	if err := json.Unmarshal(written, &result); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(result.Nodes) != len(sampleResult().Nodes) {
+		t.Errorf("expected %d nodes, got %d", len(sampleResult().Nodes), len(result.Nodes))
+	}
+	if !bytes.HasSuffix(written, []byte("}\n")) {
+		t.Errorf("expected a trailing newline, got %q", written[len(written)-3:])
+	}
+}
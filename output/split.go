@@ -0,0 +1,157 @@
+package output
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/don7panic/codewiki-go-analyzer/models"
+)
+
+// SplitIndexFile is the name of the index WriteSplit writes at the top of
+// the output directory.
+const SplitIndexFile = "index.json"
+
+// SplitPackagesDir is the subdirectory WriteSplit writes the per-package
+// files to, so that no package path can collide with SplitIndexFile.
+const SplitPackagesDir = "packages"
+
+// SplitIndex describes the files written by WriteSplit, with the metadata
+// that applies to the analysis as a whole.
+type SplitIndex struct {
+	SchemaVersion string          `json:"schema_version"`
+	GeneratedBy   string          `json:"generated_by"`
+	Partial       bool            `json:"partial,omitempty"`
+	Diagnostics   []string        `json:"diagnostics,omitempty"`
+	Summary       *models.Summary `json:"summary,omitempty"`
+	GitCommit     string          `json:"git_commit,omitempty"`
+	ModuleVersion string          `json:"module_version,omitempty"`
+	Packages      []SplitPackage  `json:"packages"` // Sorted by package
+}
+
+type SplitPackage struct {
+	Package string `json:"package"`
+	File    string `json:"file"` // Slash-separated, relative to the index
+}
+
+// SplitByPackage partitions result by package: each part holds the nodes
+// of one package, the relationships whose caller is one of them, the
+// unreferenced, entrypoints and called_by entries for them and the
+// package's doc. Nodes are grouped by PackagePath, or by directory when
+// they have none (no module). Diagnostics, imports, go:generate directives
+// and the summary are left out of the parts.
+func SplitByPackage(result models.AnalysisResult) map[string]models.AnalysisResult {
+	parts := map[string]models.AnalysisResult{}
+	packageOf := map[string]string{}
+	for _, node := range result.Nodes {
+		pkg := nodePackage(node)
+		part, ok := parts[pkg]
+		if !ok {
+			part = models.NewAnalysisResult([]models.Node{}, []models.CallRelationship{})
+			part.Partial = result.Partial
+			part.GitCommit = result.GitCommit
+			part.ModuleVersion = result.ModuleVersion
+		}
+		part.Nodes = append(part.Nodes, node)
+		parts[pkg] = part
+		packageOf[node.ID] = pkg
+		for _, method := range node.Methods {
+			packageOf[method.ID] = pkg
+		}
+	}
+
+	for _, rel := range result.CallRelationships {
+		if pkg, ok := packageOf[rel.Caller]; ok {
+			part := parts[pkg]
+			part.CallRelationships = append(part.CallRelationships, rel)
+			parts[pkg] = part
+		}
+	}
+	for _, id := range result.Unreferenced {
+		if pkg, ok := packageOf[id]; ok {
+			part := parts[pkg]
+			part.Unreferenced = append(part.Unreferenced, id)
+			parts[pkg] = part
+		}
+	}
+	for _, id := range result.Entrypoints {
+		if pkg, ok := packageOf[id]; ok {
+			part := parts[pkg]
+			part.Entrypoints = append(part.Entrypoints, id)
+			parts[pkg] = part
+		}
+	}
+	for pkg, doc := range result.PackageDocs {
+		if part, ok := parts[pkg]; ok {
+			part.PackageDocs = map[string]string{pkg: doc}
+			parts[pkg] = part
+		}
+	}
+	for callee, callers := range result.CalledBy {
+		if pkg, ok := packageOf[callee]; ok {
+			part := parts[pkg]
+			if part.CalledBy == nil {
+				part.CalledBy = map[string][]string{}
+			}
+			part.CalledBy[callee] = callers
+			parts[pkg] = part
+		}
+	}
+	return parts
+}
+
+// nodePackage returns the import path of node's package, or its
+// slash-separated directory relative to the repo root without one.
+func nodePackage(node models.Node) string {
+	if node.PackagePath != "" {
+		return node.PackagePath
+	}
+	return path.Dir(filepath.ToSlash(node.RelativePath))
+}
+
+// splitFileName maps a package to its file under the output directory:
+// the package path plus ".json" in SplitPackagesDir, so example.com/m/sub
+// is written to packages/example.com/m/sub.json. The repo root of a
+// module-less repo (".") is written to packages/_root.json.
+func splitFileName(pkg string) string {
+	if pkg == "." {
+		pkg = "_root"
+	}
+	return path.Join(SplitPackagesDir, pkg+".json")
+}
+
+// WriteSplit writes the parts of SplitByPackage to dir, one file per
+// package under SplitPackagesDir, and an index.json listing them.
+// Directories are created as needed; existing files are overwritten.
+func WriteSplit(dir string, result models.AnalysisResult, compact bool) error {
+	parts := SplitByPackage(result)
+	index := SplitIndex{
+		SchemaVersion: result.SchemaVersion,
+		GeneratedBy:   result.GeneratedBy,
+		Partial:       result.Partial,
+		Diagnostics:   result.Diagnostics,
+		Summary:       result.Summary,
+		GitCommit:     result.GitCommit,
+		ModuleVersion: result.ModuleVersion,
+		Packages:      []SplitPackage{},
+	}
+	for pkg, part := range parts {
+		name := splitFileName(pkg)
+		if err := writeJSONFile(filepath.Join(dir, filepath.FromSlash(name)), part, compact); err != nil {
+			return err
+		}
+		index.Packages = append(index.Packages, SplitPackage{Package: pkg, File: name})
+	}
+	sort.Slice(index.Packages, func(i, j int) bool {
+		return index.Packages[i].Package < index.Packages[j].Package
+	})
+	return writeJSONFile(filepath.Join(dir, SplitIndexFile), index, compact)
+}
+
+func writeJSONFile(name string, v any, compact bool) error {
+	data, err := MarshalJSON(v, compact)
+	if err != nil {
+		return err
+	}
+	return WriteFile(name, data)
+}